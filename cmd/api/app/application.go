@@ -1,11 +1,13 @@
 package app
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/config"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/jsonlog"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/jwt"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/mailer"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -21,6 +23,9 @@ type Application struct {
 	Mailer            mailer.Mailer
 	WG                sync.WaitGroup
 	GoogleOauthConfig *oauth2.Config
+	// Signer is set when access tokens are signed rather than opaque
+	Signer   *jwt.Signer
+	Denylist *Denylist
 }
 
 // InitGoogleOAuth initializes the Google OAuth configuration
@@ -36,3 +41,24 @@ func (app *Application) InitGoogleOAuth() {
 		Endpoint: google.Endpoint,
 	}
 }
+
+// InitTokenSigning loads the signing keys and the denylist when access tokens
+// are configured to be signed
+func (app *Application) InitTokenSigning() error {
+	app.Denylist = NewDenylist()
+
+	switch app.Config.Auth.TokenFormat {
+	case "opaque":
+		return nil
+	case "signed":
+		signer, err := jwt.NewSigner(app.Config.Auth.SigningKeys, app.Config.Auth.SigningKeyID)
+		if err != nil {
+			return err
+		}
+		app.Signer = signer
+
+		return app.SyncDenylist()
+	default:
+		return fmt.Errorf("unknown token format %q", app.Config.Auth.TokenFormat)
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunPeriodically calls fn every interval until ctx is cancelled. The
+// goroutine is tracked by WG, so a graceful shutdown waits for a run that is
+// in progress. Errors and panics are logged and do not stop later runs.
+func (app *Application) RunPeriodically(ctx context.Context, name string, interval time.Duration, fn func() error) {
+	app.WG.Add(1)
+	go func() {
+		defer app.WG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				app.runJob(name, fn)
+			}
+		}
+	}()
+}
+
+func (app *Application) runJob(name string, fn func() error) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.Logger.PrintError(fmt.Errorf("%s", err), map[string]string{"job": name})
+		}
+	}()
+
+	if err := fn(); err != nil {
+		app.Logger.PrintError(err, map[string]string{"job": name})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/google/uuid"
 )
 
 type contextKey string
@@ -25,3 +26,52 @@ func (app *Application) ContextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+const ideaContextKey = contextKey("idea")
+
+// ContextSetIdea adds an idea loaded by middleware to the request context
+func (app *Application) ContextSetIdea(r *http.Request, idea *data.Idea) *http.Request {
+	ctx := context.WithValue(r.Context(), ideaContextKey, idea)
+	return r.WithContext(ctx)
+}
+
+// ContextGetIdea retrieves the idea loaded by middleware from the request context
+func (app *Application) ContextGetIdea(r *http.Request) *data.Idea {
+	idea, ok := r.Context().Value(ideaContextKey).(*data.Idea)
+	if !ok {
+		panic("missing idea value in request context")
+	}
+	return idea
+}
+
+const sessionContextKey = contextKey("session")
+
+// ContextSetSessionID adds the ID of the session a request was authenticated
+// with to the request context
+func (app *Application) ContextSetSessionID(r *http.Request, id uuid.UUID) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// ContextGetSessionID retrieves the ID of the request's session, or uuid.Nil
+// when the request is anonymous
+func (app *Application) ContextGetSessionID(r *http.Request) uuid.UUID {
+	id, _ := r.Context().Value(sessionContextKey).(uuid.UUID)
+	return id
+}
+
+const permissionsContextKey = contextKey("permissions")
+
+// ContextSetPermissions adds the permissions carried by a signed access token
+// to the request context
+func (app *Application) ContextSetPermissions(r *http.Request, permissions data.Permissions) *http.Request {
+	ctx := context.WithValue(r.Context(), permissionsContextKey, permissions)
+	return r.WithContext(ctx)
+}
+
+// ContextGetPermissions retrieves the permissions carried by the request's
+// signed access token. It reports false when they have to be looked up.
+func (app *Application) ContextGetPermissions(r *http.Request) (data.Permissions, bool) {
+	permissions, ok := r.Context().Value(permissionsContextKey).(data.Permissions)
+	return permissions, ok
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Denylist holds the sessions whose signed access tokens must be rejected.
+// It is kept in memory so that signed tokens can be checked without a
+// database lookup, and is reloaded from the database periodically to pick
+// up revocations made by other instances.
+type Denylist struct {
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]time.Time
+}
+
+// NewDenylist returns an empty denylist
+func NewDenylist() *Denylist {
+	return &Denylist{sessions: make(map[uuid.UUID]time.Time)}
+}
+
+// Add denies the session until the given time
+func (d *Denylist) Add(sessionID uuid.UUID, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if until.After(d.sessions[sessionID]) {
+		d.sessions[sessionID] = until
+	}
+}
+
+// Contains reports whether the session is currently denied
+func (d *Denylist) Contains(sessionID uuid.UUID) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	until, ok := d.sessions[sessionID]
+	return ok && time.Now().Before(until)
+}
+
+// Replace swaps the denied sessions for the given set
+func (d *Denylist) Replace(sessions map[uuid.UUID]time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sessions = sessions
+}
+
+// SyncDenylist reloads the denied sessions from the database
+func (app *Application) SyncDenylist() error {
+	sessions, err := app.Models.Tokens.GetDeniedSessions()
+	if err != nil {
+		return err
+	}
+
+	app.Denylist.Replace(sessions)
+
+	return nil
+}
+
+// RevokeSignedTokens rejects the signed access tokens already issued for the
+// sessions. Opaque tokens are revoked by deleting them, so this does nothing
+// unless signed tokens are enabled.
+func (app *Application) RevokeSignedTokens(sessionIDs ...uuid.UUID) error {
+	if app.Signer == nil || len(sessionIDs) == 0 {
+		return nil
+	}
+
+	until := time.Now().Add(app.Config.Auth.AccessTokenTTL)
+
+	err := app.Models.Tokens.DenySessions(sessionIDs, until)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sessionIDs {
+		app.Denylist.Add(id, until)
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/utils"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
@@ -34,6 +35,38 @@ func (app *Application) ReadIDParam(r *http.Request) (uuid.UUID, error) {
 	return id, nil
 }
 
+// ReadUUIDParam reads and parses a UUID from the named URL parameter
+func (app *Application) ReadUUIDParam(r *http.Request, name string) (uuid.UUID, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := uuid.Parse(params.ByName(name))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s parameter", name)
+	}
+	return id, nil
+}
+
+// UserHasPermission reports whether the request's user holds the given
+// permission code. Signed access tokens carry the permissions they were
+// issued with, so the database is only consulted for opaque tokens.
+func (app *Application) UserHasPermission(r *http.Request, code string) (bool, error) {
+	user := app.ContextGetUser(r)
+	if user.IsAnonymous() {
+		return false, nil
+	}
+
+	permissions, ok := app.ContextGetPermissions(r)
+	if !ok {
+		var err error
+
+		permissions, err = app.Models.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return permissions.Include(code), nil
+}
+
 // WriteJSON writes a JSON response with the given status code and data
 func (app *Application) WriteJSON(w http.ResponseWriter, status int, data Envelope, headers http.Header) error {
 	js, err := json.Marshal(data)
@@ -110,7 +143,8 @@ func (app *Application) IsPDF(data []byte) bool {
 	return bytes.HasPrefix(data, []byte("%PDF-"))
 }
 
-// ProcessAndSavePDF processes a base64 encoded PDF and saves it to disk
+// ProcessAndSavePDF processes a base64 encoded PDF and saves it to disk. On
+// failure it writes the error response itself and returns a non-nil error.
 func (app *Application) ProcessAndSavePDF(inputBase64 string, w http.ResponseWriter, r *http.Request) (string, error) {
 	pdfData, err := base64.StdEncoding.DecodeString(inputBase64)
 	if err != nil {
@@ -119,13 +153,15 @@ func (app *Application) ProcessAndSavePDF(inputBase64 string, w http.ResponseWri
 	}
 
 	if !app.IsPDF(pdfData) {
-		app.BadRequestResponse(w, r, fmt.Errorf("invalid pdf file"))
+		err = errors.New("invalid pdf file")
+		app.BadRequestResponse(w, r, err)
 		return "no key", err
 	}
 
 	const maxPDFSize = 5 * 1024 * 1024 // 5MB
 	if len(pdfData) > maxPDFSize {
-		app.BadRequestResponse(w, r, fmt.Errorf("pdf file size must be less than 5MB"))
+		err = errors.New("pdf file size must be less than 5MB")
+		app.BadRequestResponse(w, r, err)
 		return "no key", err
 	}
 
@@ -163,7 +199,8 @@ func (app *Application) ProcessAndSaveAvatar(inputBase64 string, w http.Response
 	}
 
 	if len(imgData) < 8 {
-		app.BadRequestResponse(w, r, fmt.Errorf("invalid image file"))
+		err = errors.New("invalid image file")
+		app.BadRequestResponse(w, r, err)
 		return "", err
 	}
 
@@ -244,3 +281,55 @@ func (app *Application) ReadInt(qs url.Values, key string, defaultValue int, v *
 
 	return i
 }
+
+// ReadBool returns nil when the key is absent so callers can tell "not set" from false.
+func (app *Application) ReadBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+
+	return &b
+}
+
+// ReadTime accepts either a date (2006-01-02) or an RFC 3339 timestamp and
+// returns nil when the key is absent.
+func (app *Application) ReadTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+		if err != nil {
+			v.AddError(key, "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return nil
+		}
+	}
+
+	return &t
+}
+
+// ReadUUID returns nil when the key is absent.
+func (app *Application) ReadUUID(qs url.Values, key string, v *validator.Validator) *uuid.UUID {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	id, err := uuid.Parse(s)
+	if err != nil {
+		v.AddError(key, "must be a valid UUID")
+		return nil
+	}
+
+	return &id
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// publishBatchSize limits how many due drafts one run of
+// PublishScheduledIdeas handles; the rest are picked up by the next run.
+const publishBatchSize = 100
+
+// PublishScheduledIdeas submits for review every draft whose publish_at has
+// passed. Drafts that are still incomplete are left as drafts and their
+// schedule is cleared, so the author can finish and resubmit them.
+func (app *Application) PublishScheduledIdeas() error {
+	ideas, err := app.Models.Ideas.GetDueDrafts(publishBatchSize)
+	if err != nil || len(ideas) == 0 {
+		return err
+	}
+
+	for _, idea := range ideas {
+		categoryExists, err := app.Models.Categories.Exists(idea.Category)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if data.ValidateSubmission(v, idea, categoryExists); !v.Valid() {
+			err = app.Models.Ideas.Unschedule(idea)
+			if err != nil && !errors.Is(err, data.ErrEditConflict) {
+				return err
+			}
+
+			app.Logger.PrintInfo("scheduled idea is incomplete and was not published", map[string]string{
+				"idea_id": idea.ID.String(),
+			})
+			continue
+		}
+
+		transition := &data.StatusTransition{
+			ToStatus: data.IdeaStatusPending,
+			Reason:   "published as scheduled",
+		}
+
+		// An idea edited since it was loaded is retried on the next run
+		err = app.Models.Ideas.Transition(idea, transition)
+		if err != nil && !errors.Is(err, data.ErrEditConflict) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeTrash permanently removes the ideas, profiles and user accounts that
+// have been in the trash for longer than the retention window, together with
+// their uploaded PDFs and avatars. Ideas go first, since an account is only
+// removed once it owns none.
+func (app *Application) PurgeTrash() error {
+	before := time.Now().Add(-app.Config.Trash.Retention)
+
+	pdfs, err := app.Models.Ideas.PurgeDeleted(before)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range pdfs {
+		app.removeUploads(filepath.Join("../../uploads", filepath.Base(key)+".pdf"))
+	}
+
+	avatars, err := app.Models.UserProfile.PurgeDeleted(before)
+	if err != nil {
+		return err
+	}
+
+	// The avatar's extension depends on the image format it was uploaded in
+	for _, key := range avatars {
+		app.removeUploads(filepath.Join("../../uploads/avatars", filepath.Base(key)+".*"))
+	}
+
+	users, err := app.Models.Users.PurgeDeleted(before)
+	if err != nil {
+		return err
+	}
+
+	if len(pdfs) > 0 || len(avatars) > 0 || users > 0 {
+		app.Logger.PrintInfo("purged trash", map[string]string{
+			"pdfs":    strconv.Itoa(len(pdfs)),
+			"avatars": strconv.Itoa(len(avatars)),
+			"users":   strconv.FormatInt(users, 10),
+		})
+	}
+
+	return nil
+}
+
+// PurgeLoginFailures deletes failed logins that have left the counting
+// window and lockouts that have ended
+func (app *Application) PurgeLoginFailures() error {
+	return app.Models.LoginFailures.DeleteExpired(time.Now().Add(-app.Config.Auth.LoginFailureWindow))
+}
+
+// removeUploads deletes the uploaded files matching the pattern. The rows
+// referring to them are already gone, so failures are logged rather than
+// returned and the file is left behind.
+func (app *Application) removeUploads(pattern string) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		app.Logger.PrintError(err, map[string]string{"pattern": pattern})
+		return
+	}
+
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			app.Logger.PrintError(err, map[string]string{"file": path})
+		}
+	}
+}
@@ -40,6 +40,33 @@ type Config struct {
 		GoogleClientSecret string
 		RedirectURI        string
 	}
+	Teams struct {
+		MaxSize int
+	}
+	Feeds struct {
+		RefreshInterval time.Duration
+	}
+	Drafts struct {
+		PublishInterval time.Duration
+	}
+	Trash struct {
+		Retention     time.Duration
+		PurgeInterval time.Duration
+	}
+	Auth struct {
+		AccessTokenTTL        time.Duration
+		RefreshTokenTTL       time.Duration
+		TokenFormat           string
+		SigningKeys           string
+		SigningKeyID          string
+		DenylistSyncInterval  time.Duration
+		TwoFactorIssuer       string
+		RequireAdminTwoFactor bool
+		LoginFailureWindow    time.Duration
+		MaxAccountFailures    int
+		MaxIPFailures         int
+		LockoutDuration       time.Duration
+	}
 	FrontendURL string
 	CORS        struct {
 		TrustedOrigins []string
@@ -88,6 +115,33 @@ func LoadConfig() (*Config, error) {
 	flag.StringVar(&cfg.OAuth.GoogleClientSecret, "oauth-google-client-secret", os.Getenv("GOOGLE_CLIENT_SECRET"), "Google OAuth Client Secret")
 	flag.StringVar(&cfg.OAuth.RedirectURI, "oauth-redirect-url", os.Getenv("GOOGLE_REDIRECT_URI"), "OAuth Redirect URL")
 
+	// Team configuration
+	flag.IntVar(&cfg.Teams.MaxSize, "team-max-size", 6, "Maximum team size per idea, including the owner")
+
+	// Feed configuration
+	flag.DurationVar(&cfg.Feeds.RefreshInterval, "feeds-refresh-interval", 5*time.Minute, "How often the trending and activity feeds are recomputed")
+
+	// Draft configuration
+	flag.DurationVar(&cfg.Drafts.PublishInterval, "drafts-publish-interval", time.Minute, "How often drafts due for publication are submitted")
+
+	// Trash configuration
+	flag.DurationVar(&cfg.Trash.Retention, "trash-retention", 30*24*time.Hour, "How long deleted ideas, profiles and accounts can be restored before they are purged")
+	flag.DurationVar(&cfg.Trash.PurgeInterval, "trash-purge-interval", time.Hour, "How often deleted items past their retention are purged")
+
+	// Authentication configuration
+	flag.DurationVar(&cfg.Auth.AccessTokenTTL, "auth-access-token-ttl", 15*time.Minute, "How long an authentication token is valid")
+	flag.DurationVar(&cfg.Auth.RefreshTokenTTL, "auth-refresh-token-ttl", 30*24*time.Hour, "How long a refresh token is valid; each refresh issues a new one")
+	flag.StringVar(&cfg.Auth.TokenFormat, "auth-token-format", "opaque", "Authentication token format (opaque|signed); signed tokens are verified without a database lookup")
+	flag.StringVar(&cfg.Auth.SigningKeys, "auth-signing-keys", os.Getenv("AUTH_SIGNING_KEYS"), "Ed25519 keys for signed tokens, space separated as kid:base64-seed")
+	flag.StringVar(&cfg.Auth.SigningKeyID, "auth-signing-key-id", os.Getenv("AUTH_SIGNING_KEY_ID"), "ID of the key new signed tokens are signed with (defaults to the first key)")
+	flag.StringVar(&cfg.Auth.TwoFactorIssuer, "auth-two-factor-issuer", "OpenConnect", "Issuer name shown in authenticator apps")
+	flag.BoolVar(&cfg.Auth.RequireAdminTwoFactor, "auth-require-admin-two-factor", false, "Require admin accounts to set up two-factor authentication before they can log in")
+	flag.DurationVar(&cfg.Auth.LoginFailureWindow, "auth-login-failure-window", 15*time.Minute, "How long failed logins count towards delays and lockouts")
+	flag.IntVar(&cfg.Auth.MaxAccountFailures, "auth-max-account-failures", 10, "Failed logins for one account within the window before it is locked")
+	flag.IntVar(&cfg.Auth.MaxIPFailures, "auth-max-ip-failures", 100, "Failed logins from one IP address within the window before it is refused")
+	flag.DurationVar(&cfg.Auth.LockoutDuration, "auth-lockout-duration", 30*time.Minute, "How long a locked account stays locked unless unlocked by email")
+	flag.DurationVar(&cfg.Auth.DenylistSyncInterval, "auth-denylist-sync-interval", 10*time.Second, "How often revoked signed-token sessions are loaded from the database")
+
 	// CORS configuration
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.CORS.TrustedOrigins = strings.Fields(val)
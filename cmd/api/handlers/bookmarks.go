@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ListBookmarks lists the ideas the caller has bookmarked
+func ListBookmarks(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = appPtr.ReadString(qs, "sort", "-bookmarked_at")
+		filters.SortSafelist = []string{"bookmarked_at", "title", "votes", "-bookmarked_at", "-title", "-votes"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		ideas, metadata, err := appPtr.Models.Bookmarks.GetAllForUser(user.ID, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = setUserVotes(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "ideas": ideas}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// BookmarkIdea bookmarks an idea for the caller
+func BookmarkIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		err := appPtr.Models.Bookmarks.Insert(appPtr.ContextGetUser(r).ID, idea.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		idea.IsBookmarked = true
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UnbookmarkIdea removes an idea from the caller's bookmarks
+func UnbookmarkIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.Bookmarks.Delete(appPtr.ContextGetUser(r).ID, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "bookmark successfully removed"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+func setUserBookmarks(appPtr *app.Application, user *data.User, ideas []*data.Idea) error {
+	ids := make([]uuid.UUID, len(ideas))
+	for i, idea := range ideas {
+		ids[i] = idea.ID
+	}
+
+	bookmarked, err := appPtr.Models.Bookmarks.GetForUser(user.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, idea := range ideas {
+		idea.IsBookmarked = bookmarked[idea.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListCategories returns the category taxonomy as a tree
+func ListCategories(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		categories, err := appPtr.Models.Categories.GetAll()
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"categories": data.CategoryTree(categories)}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ShowCategory returns a single category by slug
+func ShowCategory(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, ok := readCategory(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		err := appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"category": category}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateCategory adds a category, optionally beneath a parent category
+func CreateCategory(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Slug   string `json:"slug"`
+			Name   string `json:"name"`
+			Parent string `json:"parent"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		category := &data.Category{
+			Slug:       input.Slug,
+			Name:       input.Name,
+			ParentSlug: input.Parent,
+		}
+
+		v := validator.New()
+
+		if data.ValidateCategory(v, category); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if !setCategoryParent(appPtr, w, r, category) {
+			return
+		}
+
+		err = appPtr.Models.Categories.Insert(category)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateSlug):
+				v.AddError("slug", "a category with this slug already exists")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/categories/%s", category.Slug))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"category": category}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateCategory renames a category or moves it under another parent. An
+// empty parent makes it a top-level category.
+func UpdateCategory(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, ok := readCategory(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Slug    *string `json:"slug"`
+			Name    *string `json:"name"`
+			Parent  *string `json:"parent"`
+			Version *int    `json:"version"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Version != nil && *input.Version != category.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		// Descendants are looked up under the current slug before any rename
+		descendants, err := appPtr.Models.Categories.GetSubtreeSlugs(category.Slug)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		if input.Slug != nil {
+			category.Slug = *input.Slug
+		}
+		if input.Name != nil {
+			category.Name = *input.Name
+		}
+		if input.Parent != nil {
+			category.ParentSlug = *input.Parent
+		}
+
+		v := validator.New()
+
+		data.ValidateCategory(v, category)
+		if input.Parent != nil && *input.Parent != "" {
+			v.Check(!slices.Contains(descendants, *input.Parent), "parent", "must not be the category itself or one of its subcategories")
+		}
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if !setCategoryParent(appPtr, w, r, category) {
+			return
+		}
+
+		err = appPtr.Models.Categories.Update(category)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateSlug):
+				v.AddError("slug", "a category with this slug already exists")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"category": category}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteCategory removes a category. Ideas still in it are moved to the
+// category given by the reassign_to query parameter; without it the category
+// must be unused.
+func DeleteCategory(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, ok := readCategory(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		v := validator.New()
+
+		reassignTo := appPtr.ReadString(r.URL.Query(), "reassign_to", "")
+		if reassignTo != "" {
+			v.Check(reassignTo != category.Slug, "reassign_to", "must be a different category")
+
+			_, err := appPtr.Models.Categories.Get(reassignTo)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					v.AddError("reassign_to", "must be an existing category")
+				default:
+					appPtr.ServerErrorResponse(w, r, err)
+					return
+				}
+			}
+
+			if !v.Valid() {
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+				return
+			}
+		}
+
+		err := appPtr.Models.Categories.Delete(category.Slug, reassignTo)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrCategoryInUse):
+				v.AddError("category", "still has ideas or subcategories; move them first or set reassign_to")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "category successfully deleted"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+func readCategory(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.Category, bool) {
+	slug := httprouter.ParamsFromContext(r.Context()).ByName("slug")
+
+	category, err := appPtr.Models.Categories.Get(slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return category, true
+}
+
+// setCategoryParent resolves the category's ParentSlug to a ParentID. It
+// writes a validation error if the parent does not exist.
+func setCategoryParent(appPtr *app.Application, w http.ResponseWriter, r *http.Request, category *data.Category) bool {
+	if category.ParentSlug == "" {
+		category.ParentID = nil
+		return true
+	}
+
+	parent, err := appPtr.Models.Categories.Get(category.ParentSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.FailedValidationResponse(w, r, map[string]string{"parent": "must be an existing category"})
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	category.ParentID = &parent.ID
+	return true
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListCollections lists the caller's collections
+func ListCollections(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = appPtr.ReadString(qs, "sort", "-updated_at")
+		filters.SortSafelist = []string{"name", "created_at", "updated_at", "-name", "-created_at", "-updated_at"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		collections, metadata, err := appPtr.Models.Collections.GetAllForUser(appPtr.ContextGetUser(r).ID, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "collections": collections}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateCollection creates a named collection for the caller. Collections
+// are private unless visibility is set to link.
+func CreateCollection(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Visibility  string `json:"visibility"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Visibility == "" {
+			input.Visibility = data.CollectionPrivate
+		}
+
+		collection := &data.Collection{
+			UserID:      appPtr.ContextGetUser(r).ID,
+			Name:        input.Name,
+			Description: input.Description,
+		}
+
+		err = collection.SetVisibility(input.Visibility)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateCollection(v, collection); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Collections.Insert(collection)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/collections/%s", collection.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"collection": collection}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ShowCollection returns one of the caller's collections
+func ShowCollection(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		err := appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"collection": collection}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateCollection renames a collection or changes its visibility. Making a
+// collection private revokes its share link.
+func UpdateCollection(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+			Visibility  *string `json:"visibility"`
+			Version     *int    `json:"version"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Version != nil && *input.Version != collection.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		if input.Name != nil {
+			collection.Name = *input.Name
+		}
+		if input.Description != nil {
+			collection.Description = *input.Description
+		}
+		if input.Visibility != nil {
+			err = collection.SetVisibility(*input.Visibility)
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		v := validator.New()
+
+		if data.ValidateCollection(v, collection); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Collections.Update(collection)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"collection": collection}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteCollection deletes one of the caller's collections. The ideas in it are not affected.
+func DeleteCollection(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		err := appPtr.Models.Collections.Delete(collection.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "collection successfully deleted"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListCollectionIdeas lists the ideas in one of the caller's collections
+func ListCollectionIdeas(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		writeCollectionIdeas(appPtr, w, r, collection, false)
+	}
+}
+
+// AddCollectionIdea puts an idea into one of the caller's collections
+func AddCollectionIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		ideaID, err := appPtr.ReadUUIDParam(r, "idea_id")
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		idea, err := appPtr.Models.Ideas.Get(ideaID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !data.IsPublicStatus(idea.Status) && idea.UserID != collection.UserID {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.Collections.AddIdea(collection.ID, idea.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "idea successfully added to collection"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RemoveCollectionIdea takes an idea out of one of the caller's collections
+func RemoveCollectionIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := readOwnedCollection(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		ideaID, err := appPtr.ReadUUIDParam(r, "idea_id")
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.Collections.RemoveIdea(collection.ID, ideaID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "idea successfully removed from collection"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ShowSharedCollection returns a collection shared by link together with a
+// page of its public ideas. No account is needed to follow a share link.
+func ShowSharedCollection(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+		collection, err := appPtr.Models.Collections.GetByShareToken(token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		writeCollectionIdeas(appPtr, w, r, collection, true)
+	}
+}
+
+// writeCollectionIdeas writes a page of the ideas in a collection that the
+// caller may see. Shared views include the collection itself.
+func writeCollectionIdeas(appPtr *app.Application, w http.ResponseWriter, r *http.Request, collection *data.Collection, shared bool) {
+	var filters data.Filters
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+	filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+	filters.Sort = appPtr.ReadString(qs, "sort", "-added_at")
+	filters.SortSafelist = []string{"added_at", "title", "votes", "-added_at", "-title", "-votes"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		appPtr.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := appPtr.ContextGetUser(r)
+
+	ideas, metadata, err := appPtr.Models.Collections.GetIdeas(collection.ID, user.ID, filters)
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if !user.IsAnonymous() {
+		err = setUserVotes(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = setUserBookmarks(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	env := app.Envelope{"metadata": metadata, "ideas": ideas}
+	if shared {
+		collection.ShareToken = ""
+		env["collection"] = collection
+	}
+
+	err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+	}
+}
+
+// readOwnedCollection loads the collection named by the :id URL parameter.
+// Collections belonging to other users are reported as not found.
+func readOwnedCollection(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.Collection, bool) {
+	id, err := appPtr.ReadIDParam(r)
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	collection, err := appPtr.Models.Collections.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if collection.UserID != appPtr.ContextGetUser(r).ID {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return collection, true
+}
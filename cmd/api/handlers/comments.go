@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ListComments lists the comment threads on an idea
+func ListComments(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ideaID, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		_, err = appPtr.Models.Ideas.Get(ideaID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = appPtr.ReadString(qs, "sort", "-created_at")
+		filters.SortSafelist = []string{"created_at", "-created_at"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		comments, metadata, err := appPtr.Models.Comments.GetAllForIdea(ideaID, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "comments": comments}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateComment adds a comment, or a reply to an existing comment, on an idea
+func CreateComment(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ideaID, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		_, err = appPtr.Models.Ideas.Get(ideaID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var input struct {
+			Content  string     `json:"content"`
+			ParentID *uuid.UUID `json:"parent_id"`
+		}
+
+		err = appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		comment := &data.Comment{
+			IdeaID:      ideaID,
+			CommentedBy: user.ID,
+			ParentID:    input.ParentID,
+			Content:     input.Content,
+		}
+
+		v := validator.New()
+
+		if input.ParentID != nil {
+			parent, err := appPtr.Models.Comments.Get(*input.ParentID)
+			if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+			v.Check(parent != nil && parent.IdeaID == ideaID, "parent_id", "must reference a comment on the same idea")
+		}
+
+		if data.ValidateComment(v, comment); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Comments.Insert(comment)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s/comments/%s", ideaID, comment.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"comment": comment}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateComment lets the author edit the content of their comment
+func UpdateComment(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		comment, ok := readIdeaComment(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+		if comment.CommentedBy != user.ID {
+			appPtr.NotPermittedResponse(w, r)
+			return
+		}
+
+		var input struct {
+			Content *string `json:"content"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Content != nil {
+			comment.Content = *input.Content
+		}
+
+		v := validator.New()
+
+		if data.ValidateComment(v, comment); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Comments.Update(comment)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"comment": comment}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteComment removes a comment and its replies; allowed for the author or holders of ideas:admin
+func DeleteComment(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		comment, ok := readIdeaComment(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+		if comment.CommentedBy != user.ID {
+			isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+			if !isAdmin {
+				appPtr.NotPermittedResponse(w, r)
+				return
+			}
+		}
+
+		err := appPtr.Models.Comments.Delete(comment.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "comment deleted successfully"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// readIdeaComment loads the comment named in the URL and makes sure it belongs
+// to the idea in the URL. It writes the error response itself and reports
+// whether the handler should continue.
+func readIdeaComment(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.Comment, bool) {
+	ideaID, err := appPtr.ReadIDParam(r)
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	commentID, err := appPtr.ReadUUIDParam(r, "comment_id")
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	comment, err := appPtr.Models.Comments.Get(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if comment.IdeaID != ideaID {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return comment, true
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ShowIdeaOrFeed serves GET /v1/ideas/:id. httprouter cannot register
+// /v1/ideas/trending or /v1/ideas/activity next to /v1/ideas/:id, so the
+// feed names are matched here and anything else is shown as an idea.
+func ShowIdeaOrFeed(appPtr *app.Application) http.HandlerFunc {
+	showIdea := ShowIdea(appPtr)
+	trending := ListTrendingIdeas(appPtr)
+	activity := ListActiveIdeas(appPtr)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch httprouter.ParamsFromContext(r.Context()).ByName("id") {
+		case "trending":
+			trending(w, r)
+		case "activity":
+			activity(w, r)
+		default:
+			showIdea(w, r)
+		}
+	}
+}
+
+// ListTrendingIdeas ranks public ideas by recent unique views, edits and
+// creation, with views counting the most
+func ListTrendingIdeas(appPtr *app.Application) http.HandlerFunc {
+	return listIdeaFeed(appPtr, data.FeedTrending)
+}
+
+// ListActiveIdeas ranks public ideas by how recently and how much they have
+// been created and edited
+func ListActiveIdeas(appPtr *app.Application) http.HandlerFunc {
+	return listIdeaFeed(appPtr, data.FeedActivity)
+}
+
+// listIdeaFeed serves a feed over the period given by the period query
+// parameter (24h, 7d or 30d). Feeds are recomputed periodically, so recent
+// activity may take a few minutes to show.
+func listIdeaFeed(appPtr *app.Application, feed string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		period := appPtr.ReadString(qs, "period", "7d")
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = "-score"
+		filters.SortSafelist = []string{"-score"}
+
+		v.Check(validator.PermittedValue(period, data.FeedPeriods...), "period", "must be one of 24h, 7d or 30d")
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		entries, metadata, err := appPtr.Models.Ideas.GetFeed(feed, period, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		ideas := make([]*data.Idea, len(entries))
+		for i, entry := range entries {
+			ideas[i] = entry.Idea
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		err = setUserVotes(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = setUserBookmarks(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "period": period, "ideas": entries}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// recordIdeaView counts the request as a view of the idea in the background.
+// Viewers are told apart by user, or by IP when not logged in, and owners
+// viewing their own ideas are not counted.
+func recordIdeaView(appPtr *app.Application, r *http.Request, user *data.User, ideaID, ownerID uuid.UUID) {
+	var viewer string
+
+	switch {
+	case !user.IsAnonymous() && user.ID == ownerID:
+		return
+	case !user.IsAnonymous():
+		viewer = user.ID.String()
+	default:
+		viewer = requestIP(r)
+	}
+
+	appPtr.WG.Add(1)
+	go func() {
+		defer appPtr.WG.Done()
+
+		err := appPtr.Models.Ideas.RecordView(ideaID, viewer)
+		if err != nil {
+			appPtr.Logger.PrintError(err, nil)
+		}
+	}()
+}
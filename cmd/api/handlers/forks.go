@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// ForkIdea copies an idea into the caller's account as a draft whose
+// idea_source_id points back at the original
+func ForkIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		source, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if source.UserID == user.ID {
+			appPtr.FailedValidationResponse(w, r, map[string]string{"idea": "you cannot fork your own idea"})
+			return
+		}
+
+		idea := data.NewFork(source, user.ID)
+
+		categoryExists, err := appPtr.Models.Categories.Exists(idea.Category)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateIdea(v, idea, categoryExists); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Ideas.Insert(idea)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s", idea.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"idea": idea}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListIdeaForks lists the ideas forked directly from an idea. Forks that have
+// not passed moderation are only listed for moderators.
+func ListIdeaForks(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = appPtr.ReadString(qs, "sort", "-created_at")
+		filters.SortSafelist = []string{"created_at", "title", "votes", "-created_at", "-title", "-votes"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		statuses := data.PublicIdeaStatuses
+		if canModerate {
+			statuses = []string{}
+		}
+
+		forks, metadata, err := appPtr.Models.Ideas.GetForks(idea.ID, statuses, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "forks": forks}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ShowIdeaLineage returns the chain of ideas an idea was forked from, nearest
+// ancestor first. Ancestors the caller may not see are left out.
+func ShowIdeaLineage(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		ancestors, err := appPtr.Models.Ideas.GetLineage(idea)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		lineage := []*data.Idea{}
+		for _, ancestor := range ancestors {
+			if canModerate || ancestor.UserID == user.ID || data.IsPublicStatus(ancestor.Status) {
+				lineage = append(lineage, ancestor)
+			}
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea, "lineage": lineage}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// ChangeIdeaStatus moves an idea through the status workflow. Approving or
+// rejecting a pending idea requires ideas:moderate; every other transition is
+// open to the idea's owner, moderators and holders of ideas:admin.
+func ChangeIdeaStatus(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		idea, err := appPtr.Models.Ideas.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var input struct {
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Version *int   `json:"version"`
+		}
+
+		err = appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		transition := &data.StatusTransition{
+			ToStatus:  input.Status,
+			Reason:    input.Reason,
+			ChangedBy: &user.ID,
+		}
+
+		v := validator.New()
+
+		if data.ValidateStatusTransition(v, transition); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		if data.IsModerationTransition(idea.Status, input.Status) && !canModerate {
+			appPtr.NotPermittedResponse(w, r)
+			return
+		}
+
+		if idea.UserID != user.ID && !canModerate {
+			isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+			if !isAdmin {
+				appPtr.NotPermittedResponse(w, r)
+				return
+			}
+		}
+
+		if input.Version != nil && *input.Version != idea.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		// Drafts are saved with lenient validation, so they must be complete
+		// before they can be submitted for review
+		if idea.Status == data.IdeaStatusDraft && input.Status == data.IdeaStatusPending {
+			categoryExists, err := appPtr.Models.Categories.Exists(idea.Category)
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+
+			if data.ValidateSubmission(v, idea, categoryExists); !v.Valid() {
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+				return
+			}
+		}
+
+		err = appPtr.Models.Ideas.Transition(idea, transition)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidTransition):
+				v.AddError("status", "cannot change status from "+idea.Status+" to "+input.Status)
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea, "transition": transition}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListIdeaTransitions returns the status history of an idea
+func ListIdeaTransitions(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		idea, err := appPtr.Models.Ideas.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if idea.UserID != user.ID {
+			canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+			if !canModerate {
+				appPtr.NotPermittedResponse(w, r)
+				return
+			}
+		}
+
+		transitions, err := appPtr.Models.Ideas.GetTransitions(idea.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"transitions": transitions}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListModerationQueue lists ideas waiting for a moderation decision, oldest first
+func ListModerationQueue(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = appPtr.ReadString(qs, "sort", "created_at")
+		filters.SortSafelist = []string{"created_at", "title", "category", "-created_at", "-title", "-category"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		ideas, metadata, err := appPtr.Models.Ideas.GetAllIdeas(data.IdeaFilter{Statuses: []string{data.IdeaStatusPending}}, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "ideas": ideas}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
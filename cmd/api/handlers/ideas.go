@@ -4,6 +4,7 @@ import (
     "errors"
     "fmt"
     "net/http"
+    "time"
 
     "github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
     "github.com/OpenConnectOUSL/backend-api-v1/internal/data"
@@ -11,20 +12,25 @@ import (
     "github.com/google/uuid"
 )
 
-// CreateIdea creates a new idea
+// CreateIdea creates a new idea. The response lists existing ideas that look
+// like duplicates of it; with dry_run=true only that list is returned and the
+// idea is not saved. Ideas saved as drafts may set publish_at to be submitted
+// for review automatically at that time.
 func CreateIdea(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         var input struct {
-            Title            string   `json:"title"`
-            Description      string   `json:"description"`
-            PDF              string   `json:"pdf"`
-            Category         string   `json:"category"`
-            Tags             []string `json:"tags"`
-            UserID           string   `json:"user_id"`
-            LearningOutcome  string   `json:"learning_outcome"`
-            RecommendedLevel string   `json:"recommended_level"`
-            GitHubLink       string   `json:"github_link"`
-            WebsiteLink      string   `json:"website_link"`
+            Title            string     `json:"title"`
+            Description      string     `json:"description"`
+            PDF              string     `json:"pdf"`
+            Category         string     `json:"category"`
+            Tags             []string   `json:"tags"`
+            UserID           string     `json:"user_id"`
+            LearningOutcome  string     `json:"learning_outcome"`
+            RecommendedLevel string     `json:"recommended_level"`
+            GitHubLink       string     `json:"github_link"`
+            WebsiteLink      string     `json:"website_link"`
+            Draft            bool       `json:"draft"`
+            PublishAt        *time.Time `json:"publish_at"`
         }
 
         err := appPtr.ReadJSON(w, r, &input)
@@ -51,6 +57,19 @@ func CreateIdea(appPtr *app.Application) http.HandlerFunc {
             userID = user.ID
         }
 
+        // Only admins may submit an idea on behalf of another user
+        if userID != user.ID {
+            isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+                return
+            }
+            if !isAdmin {
+                appPtr.NotPermittedResponse(w, r)
+                return
+            }
+        }
+
         // Get existing profile
         profile, err := appPtr.Models.UserProfile.GetByUserID(user.ID)
         if err != nil {
@@ -64,32 +83,70 @@ func CreateIdea(appPtr *app.Application) http.HandlerFunc {
         }
         fmt.Println("Profile ID:", profile)
 
-        pdfID, err := appPtr.ProcessAndSavePDF(input.PDF, w, r)
-        if err != nil {
-            appPtr.ServerErrorResponse(w, r, err)
-            return
-        }
-
         idea := &data.Idea{
             Title:            input.Title,
             Description:      input.Description,
             Category:         input.Category,
             Tags:             input.Tags,
             UserID:           userID,
-            IdeaSourceID:     pdfID,
             LearningOutcome:  input.LearningOutcome,
             RecommendedLevel: input.RecommendedLevel,
             GitHubLink:       input.GitHubLink,
             WebsiteLink:      input.WebsiteLink,
+            PublishAt:        input.PublishAt,
+        }
+
+        // Drafts stay private to their author and are only checked leniently
+        if input.Draft {
+            idea.Status = data.IdeaStatusDraft
+        }
+
+        categoryExists, err := appPtr.Models.Categories.Exists(idea.Category)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
         }
 
         v := validator.New()
 
-        if data.ValidateIdea(v, idea); !v.Valid() {
+        dryRun := appPtr.ReadBool(r.URL.Query(), "dry_run", v)
+
+        if input.PublishAt != nil {
+            v.Check(input.PublishAt.After(time.Now()), "publish_at", "must be in the future")
+        }
+
+        v.Check(input.Draft || input.PDF != "", "pdf", "must be provided")
+
+        if data.ValidateIdea(v, idea, categoryExists); !v.Valid() {
             appPtr.FailedValidationResponse(w, r, v.Errors)
             return
         }
 
+        // Similar existing ideas are reported so the author can check they
+        // are not proposing something already submitted
+        duplicates, err := appPtr.Models.Ideas.FindDuplicates(idea)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        // A dry run only checks for duplicates and saves nothing
+        if dryRun != nil && *dryRun {
+            err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"potential_duplicates": duplicates}, nil)
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        // Drafts may be saved before a PDF is attached
+        if input.PDF != "" {
+            idea.Pdf, err = appPtr.ProcessAndSavePDF(input.PDF, w, r)
+            if err != nil {
+                return
+            }
+        }
+
         err = appPtr.Models.Ideas.Insert(idea)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
@@ -99,7 +156,7 @@ func CreateIdea(appPtr *app.Application) http.HandlerFunc {
         headers := make(http.Header)
         headers.Set("Location", fmt.Sprintf("/v1/ideas/%d", idea.ID))
 
-        err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"idea": idea}, headers)
+        err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"idea": idea, "potential_duplicates": duplicates}, headers)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
         }
@@ -111,23 +168,34 @@ func CreateIdea(appPtr *app.Application) http.HandlerFunc {
 // ShowIdea retrieves a specific idea by ID
 func ShowIdea(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        id, err := appPtr.ReadIDParam(r)
+        idea, ok := readVisibleIdea(appPtr, w, r)
+        if !ok {
+            return
+        }
+
+        user := appPtr.ContextGetUser(r)
+
+        votes, err := appPtr.Models.Votes.GetForUser(user.ID, []uuid.UUID{idea.ID})
         if err != nil {
-            appPtr.NotFoundResponse(w, r)
+            appPtr.ServerErrorResponse(w, r, err)
             return
         }
+        idea.UserVote = votes[idea.ID]
 
-        idea, err := appPtr.Models.Ideas.Get(id)
+        err = setUserBookmarks(appPtr, user, []*data.Idea{idea})
         if err != nil {
-            switch {
-            case errors.Is(err, data.ErrRecordNotFound):
-                appPtr.NotFoundResponse(w, r)
-            default:
-                appPtr.ServerErrorResponse(w, r, err)
-            }
+            appPtr.ServerErrorResponse(w, r, err)
             return
         }
 
+        idea.Progress, err = appPtr.Models.Tasks.GetProgress(idea.ID)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        recordIdeaView(appPtr, r, user, idea.ID, idea.UserID)
+
         err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea}, nil)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
@@ -136,24 +204,10 @@ func ShowIdea(appPtr *app.Application) http.HandlerFunc {
 }
 
 // UpdateIdea updates an existing idea
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
 func UpdateIdea(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        id, err := appPtr.ReadIDParam(r)
-        if err != nil {
-            appPtr.NotFoundResponse(w, r)
-            return
-        }
-
-        idea, err := appPtr.Models.Ideas.Get(id)
-        if err != nil {
-            switch {
-            case errors.Is(err, data.ErrRecordNotFound):
-                appPtr.NotFoundResponse(w, r)
-            default:
-                appPtr.ServerErrorResponse(w, r, err)
-            }
-            return
-        }
+        idea := appPtr.ContextGetIdea(r)
 
         var input struct {
             Title            *string  `json:"title"`
@@ -165,10 +219,10 @@ func UpdateIdea(appPtr *app.Application) http.HandlerFunc {
             RecommendedLevel *string  `json:"recommended_level"`
             GitHubLink       *string  `json:"github_link"`
             WebsiteLink      *string  `json:"website_link"`
-            Status           *string  `json:"status"`
+            PublishAt        *string  `json:"publish_at"`
         }
 
-        err = appPtr.ReadJSON(w, r, &input)
+        err := appPtr.ReadJSON(w, r, &input)
         if err != nil {
             appPtr.BadRequestResponse(w, r, err)
             return
@@ -179,7 +233,6 @@ func UpdateIdea(appPtr *app.Application) http.HandlerFunc {
         if input.PdfBase64 != nil && *input.PdfBase64 != "" {
             uniqueID, err = appPtr.ProcessAndSavePDF(*input.PdfBase64, w, r)
             if err != nil {
-                appPtr.ServerErrorResponse(w, r, err)
                 return
             }
         }
@@ -220,9 +273,29 @@ func UpdateIdea(appPtr *app.Application) http.HandlerFunc {
             idea.WebsiteLink = *input.WebsiteLink
         }
 
+        categoryExists, err := appPtr.Models.Categories.Exists(idea.Category)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
         v := validator.New()
 
-        if data.ValidateIdea(v, idea); !v.Valid() {
+        // An empty publish_at cancels a scheduled publication
+        if input.PublishAt != nil {
+            idea.PublishAt = nil
+            if *input.PublishAt != "" {
+                publishAt, err := time.Parse(time.RFC3339, *input.PublishAt)
+                if err != nil {
+                    v.AddError("publish_at", "must be an RFC 3339 timestamp")
+                } else {
+                    v.Check(publishAt.After(time.Now()), "publish_at", "must be in the future")
+                    idea.PublishAt = &publishAt
+                }
+            }
+        }
+
+        if data.ValidateIdea(v, idea, categoryExists); !v.Valid() {
             appPtr.FailedValidationResponse(w, r, v.Errors)
             return
         }
@@ -245,16 +318,14 @@ func UpdateIdea(appPtr *app.Application) http.HandlerFunc {
     }
 }
 
-// DeleteIdea deletes an idea by ID
+// DeleteIdea moves an idea to its owner's trash, from which it can be
+// restored until it is purged
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
 func DeleteIdea(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        id, err := appPtr.ReadIDParam(r)
-        if err != nil {
-            appPtr.NotFoundResponse(w, r)
-            return
-        }
+        idea := appPtr.ContextGetIdea(r)
 
-        err = appPtr.Models.Ideas.Delete(id)
+        err := appPtr.Models.Ideas.Delete(idea.ID)
         if err != nil {
             switch {
             case errors.Is(err, data.ErrRecordNotFound):
@@ -265,20 +336,27 @@ func DeleteIdea(appPtr *app.Application) http.HandlerFunc {
             return
         }
 
-        err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "idea deleted successfully"}, nil)
+        env := app.Envelope{
+            "message":  "idea moved to trash",
+            "purge_at": time.Now().Add(appPtr.Config.Trash.Retention),
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
         }
     }
 }
 
-// ListIdeas lists ideas with filtering and pagination
+// ListIdeas lists ideas with filtering and pagination. The metadata includes
+// facet counts per category, tag and recommended level for the same filters.
+// With the cursor parameter pages are read by keyset instead of page number
+// and the metadata links to the next and previous pages by cursor
 func ListIdeas(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         var input struct {
-            Title    string
             Category string
-            Tags     []string
+            data.IdeaFilter
             data.Filters
         }
 
@@ -286,21 +364,105 @@ func ListIdeas(appPtr *app.Application) http.HandlerFunc {
 
         qs := r.URL.Query()
 
+        input.Query = appPtr.ReadString(qs, "q", "")
         input.Title = appPtr.ReadString(qs, "title", "")
         input.Category = appPtr.ReadString(qs, "category", "")
         input.Tags = appPtr.ReadCSV(qs, "tags", []string{})
+        input.Statuses = appPtr.ReadCSV(qs, "status", []string{})
+        input.RecommendedLevels = appPtr.ReadCSV(qs, "recommended_level", []string{})
+        input.UserID = appPtr.ReadUUID(qs, "user_id", v)
+        input.CreatedAfter = appPtr.ReadTime(qs, "created_after", v)
+        input.CreatedBefore = appPtr.ReadTime(qs, "created_before", v)
+        input.HasGitHubLink = appPtr.ReadBool(qs, "has_github_link", v)
 
         input.Filters.Page = appPtr.ReadInt(qs, "page", 1, v)
         input.Filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
         input.Filters.Sort = appPtr.ReadString(qs, "sort", "id")
-        input.Filters.SortSafelist = []string{"id", "title", "category", "-id", "-title", "-category"}
+
+        // Passing cursor, even empty, switches to keyset pagination
+        input.Filters.Keyset = qs.Has("cursor")
+        input.Filters.Cursor = appPtr.ReadString(qs, "cursor", "")
+        input.Filters.SortSafelist = []string{"id", "title", "category", "votes", "created_at", "-id", "-title", "-category", "-votes", "-created_at"}
+
+        // Search results are ranked by relevance unless another order is asked for
+        if input.Query != "" {
+            input.Filters.Sort = appPtr.ReadString(qs, "sort", "-relevance")
+            input.Filters.SortSafelist = append(input.Filters.SortSafelist, "-relevance")
+        }
+
+        v.Check(len(input.Query) <= 200, "q", "must not be more than 200 bytes long")
+
+        for _, status := range input.Statuses {
+            v.Check(validator.PermittedValue(status, data.IdeaStatuses...), "status", "invalid status value")
+        }
+
+        if input.CreatedAfter != nil && input.CreatedBefore != nil {
+            v.Check(input.CreatedAfter.Before(*input.CreatedBefore), "created_before", "must be later than created_after")
+        }
 
         if data.ValidateFilters(v, input.Filters); !v.Valid() {
             appPtr.FailedValidationResponse(w, r, v.Errors)
             return
         }
 
-        ideas, metadata, err := appPtr.Models.Ideas.GetAllIdeas(input.Title, input.Tags, input.Filters)
+        // Only moderators may see ideas that have not passed moderation
+        canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        if !canModerate {
+            for _, status := range input.Statuses {
+                v.Check(data.IsPublicStatus(status), "status", "you may only filter on approved, in_progress or completed")
+            }
+            if !v.Valid() {
+                appPtr.FailedValidationResponse(w, r, v.Errors)
+                return
+            }
+            if len(input.Statuses) == 0 {
+                input.Statuses = data.PublicIdeaStatuses
+            }
+        }
+
+        // A category filter also matches ideas in its subcategories
+        if input.Category != "" {
+            input.Categories, err = appPtr.Models.Categories.GetSubtreeSlugs(input.Category)
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+                return
+            }
+            if len(input.Categories) == 0 {
+                v.AddError("category", "must be an existing category")
+                appPtr.FailedValidationResponse(w, r, v.Errors)
+                return
+            }
+        }
+
+        ideas, metadata, err := appPtr.Models.Ideas.GetAllIdeas(input.IdeaFilter, input.Filters)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrInvalidCursor):
+                appPtr.FailedValidationResponse(w, r, map[string]string{"cursor": "must be a cursor returned by a previous page"})
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        metadata.Facets, err = appPtr.Models.Ideas.GetFacets(input.IdeaFilter)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = setUserVotes(appPtr, appPtr.ContextGetUser(r), ideas)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = setUserBookmarks(appPtr, appPtr.ContextGetUser(r), ideas)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
             return
@@ -311,4 +473,109 @@ func ListIdeas(appPtr *app.Application) http.HandlerFunc {
             appPtr.ServerErrorResponse(w, r, err)
         }
     }
-}
\ No newline at end of file
+}
+
+// ListMyIdeas lists the ideas the caller owns or works on as a team member,
+// including drafts and ideas still under review
+func ListMyIdeas(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var filters data.Filters
+
+        v := validator.New()
+        qs := r.URL.Query()
+
+        statuses := appPtr.ReadCSV(qs, "status", []string{})
+
+        filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+        filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+        filters.Sort = appPtr.ReadString(qs, "sort", "-created_at")
+        filters.SortSafelist = []string{"title", "votes", "created_at", "-title", "-votes", "-created_at"}
+        filters.Keyset = qs.Has("cursor")
+        filters.Cursor = appPtr.ReadString(qs, "cursor", "")
+
+        for _, status := range statuses {
+            v.Check(validator.PermittedValue(status, data.IdeaStatuses...), "status", "invalid status value")
+        }
+
+        if data.ValidateFilters(v, filters); !v.Valid() {
+            appPtr.FailedValidationResponse(w, r, v.Errors)
+            return
+        }
+
+        user := appPtr.ContextGetUser(r)
+
+        ideas, metadata, err := appPtr.Models.Ideas.GetAllByUserID(user.ID, statuses, filters)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrInvalidCursor):
+                appPtr.FailedValidationResponse(w, r, map[string]string{"cursor": "must be a cursor returned by a previous page"})
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        err = setUserVotes(appPtr, user, ideas)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = setUserBookmarks(appPtr, user, ideas)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "ideas": ideas}, nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
+// readVisibleIdea loads the idea named by the :id URL parameter and checks that
+// the user may see it: ideas that have not passed moderation are only visible
+// to their owner, their team and moderators. It writes the error response itself and
+// reports whether the handler should continue.
+func readVisibleIdea(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.Idea, bool) {
+    id, err := appPtr.ReadIDParam(r)
+    if err != nil {
+        appPtr.NotFoundResponse(w, r)
+        return nil, false
+    }
+
+    idea, err := appPtr.Models.Ideas.Get(id)
+    if err != nil {
+        switch {
+        case errors.Is(err, data.ErrRecordNotFound):
+            appPtr.NotFoundResponse(w, r)
+        default:
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+        return nil, false
+    }
+
+    user := appPtr.ContextGetUser(r)
+
+    if !data.IsPublicStatus(idea.Status) && idea.UserID != user.ID {
+        canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return nil, false
+        }
+
+        isMember, err := appPtr.Models.Teams.IsMember(idea.ID, user.ID)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return nil, false
+        }
+
+        if !canModerate && !isMember {
+            appPtr.NotFoundResponse(w, r)
+            return nil, false
+        }
+    }
+
+    return idea, true
+}
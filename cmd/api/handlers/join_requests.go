@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// CreateJoinRequest asks to join the team working on an idea. Skills default
+// to everything on the requester's profile. The idea's owner is notified by email.
+func CreateJoinRequest(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := readCurrentUser(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Message string    `json:"message"`
+			Skills  *[]string `json:"skills"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		profileSkills, err := appPtr.Models.UserProfile.GetSkills(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		req := &data.JoinRequest{
+			IdeaID:   idea.ID,
+			UserID:   user.ID,
+			UserName: user.UserName,
+			Message:  input.Message,
+			Skills:   profileSkills,
+		}
+
+		if input.Skills != nil {
+			req.Skills = *input.Skills
+		}
+
+		isMember, err := appPtr.Models.Teams.IsMember(idea.ID, user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		v.Check(idea.UserID != user.ID, "idea", "you cannot request to join your own idea")
+		v.Check(!isMember, "idea", "you are already on this idea's team")
+
+		if data.ValidateJoinRequest(v, req, profileSkills); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.JoinRequests.Insert(req)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateJoinRequest):
+				v.AddError("idea", "you already have a pending request to join this idea")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		appPtr.WG.Add(1)
+		go func() {
+			defer appPtr.WG.Done()
+
+			owner, err := appPtr.Models.Users.Get(idea.UserID)
+			if err != nil {
+				appPtr.Logger.PrintError(err, nil)
+				return
+			}
+
+			emailData := map[string]any{
+				"ownerName":     owner.UserName,
+				"requesterName": user.UserName,
+				"ideaID":        idea.ID,
+				"ideaTitle":     idea.Title,
+				"message":       req.Message,
+				"skills":        req.Skills,
+				"frontendURL":   appPtr.Config.FrontendURL,
+			}
+			err = appPtr.Mailer.Send(owner.Email, "join_request", emailData)
+			if err != nil {
+				appPtr.Logger.PrintError(err, nil)
+			}
+		}()
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s/join-requests/%s", idea.ID, req.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"join_request": req}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListJoinRequests lists the requests to join an idea's team, optionally
+// filtered by status. The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func ListJoinRequests(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea := appPtr.ContextGetIdea(r)
+
+		var input struct {
+			Statuses []string
+			data.Filters
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		input.Statuses = appPtr.ReadCSV(qs, "status", []string{})
+		input.Filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		input.Filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		input.Filters.Sort = "-created_at"
+		input.Filters.SortSafelist = []string{"-created_at"}
+
+		for _, status := range input.Statuses {
+			v.Check(validator.PermittedValue(status, data.JoinRequestStatuses...), "status", "invalid status value")
+		}
+
+		if data.ValidateFilters(v, input.Filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		requests, metadata, err := appPtr.Models.JoinRequests.GetAllForIdea(idea.ID, input.Statuses, input.Filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "join_requests": requests}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// AcceptJoinRequest accepts a pending request and adds the requester to the team.
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func AcceptJoinRequest(appPtr *app.Application) http.HandlerFunc {
+	return respondToJoinRequest(appPtr, data.JoinRequestAccepted)
+}
+
+// RejectJoinRequest turns down a pending request.
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func RejectJoinRequest(appPtr *app.Application) http.HandlerFunc {
+	return respondToJoinRequest(appPtr, data.JoinRequestRejected)
+}
+
+func respondToJoinRequest(appPtr *app.Application, status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea := appPtr.ContextGetIdea(r)
+
+		req, ok := readJoinRequest(appPtr, w, r, idea.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Response string `json:"response"`
+			Version  *int   `json:"version"`
+		}
+
+		// The body is optional
+		if r.ContentLength != 0 {
+			err := appPtr.ReadJSON(w, r, &input)
+			if err != nil {
+				appPtr.BadRequestResponse(w, r, err)
+				return
+			}
+		}
+
+		if input.Version != nil && *input.Version != req.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		v.Check(req.Status == data.JoinRequestPending, "status", "this request has already been answered")
+		v.Check(len(input.Response) <= 500, "response", "must not be more than 500 bytes long")
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		req.Response = input.Response
+
+		var err error
+		if status == data.JoinRequestAccepted {
+			err = appPtr.Models.JoinRequests.Accept(req, appPtr.Config.Teams.MaxSize)
+		} else {
+			err = appPtr.Models.JoinRequests.Reject(req)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrTeamFull):
+				v.AddError("team", fmt.Sprintf("must not have more than %d members", appPtr.Config.Teams.MaxSize))
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"join_request": req}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// readJoinRequest loads the request named by the :request_id URL parameter,
+// writing a 404 if it does not exist or belongs to another idea.
+func readJoinRequest(appPtr *app.Application, w http.ResponseWriter, r *http.Request, ideaID uuid.UUID) (*data.JoinRequest, bool) {
+	id, err := appPtr.ReadUUIDParam(r, "request_id")
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	req, err := appPtr.Models.JoinRequests.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if req.IdeaID != ideaID {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return req, true
+}
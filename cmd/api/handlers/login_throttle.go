@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+)
+
+const (
+	// freeLoginFailures is how many failed logins an account gets before
+	// each further attempt has to wait
+	freeLoginFailures = 3
+	// maxLoginDelay caps the wait between attempts, which doubles with each
+	// failure past the free ones
+	maxLoginDelay = time.Minute
+)
+
+// loginDelay is how long after its latest failure an account must wait
+// before the next attempt
+func loginDelay(failures int) time.Duration {
+	if failures < freeLoginFailures {
+		return 0
+	}
+
+	exponent := min(failures-freeLoginFailures, 16)
+	delay := time.Duration(math.Pow(2, float64(exponent))) * time.Second
+
+	return min(delay, maxLoginDelay)
+}
+
+// checkLoginAllowed refuses a login attempt for a locked account, from an IP
+// address with too many recent failures, or made before the account's
+// progressive delay has passed. The email is tracked whether or not an
+// account exists for it, so responses do not reveal which emails are
+// registered. It writes the error response itself and reports whether the
+// handler should continue.
+func checkLoginAllowed(appPtr *app.Application, w http.ResponseWriter, r *http.Request, email string) bool {
+	ip := requestIP(r)
+	now := time.Now()
+
+	status, err := appPtr.Models.LoginFailures.GetStatus(email, ip, now.Add(-appPtr.Config.Auth.LoginFailureWindow))
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+		return false
+	}
+
+	var (
+		retryAt time.Time
+		reason  string
+		message string
+	)
+
+	switch {
+	case status.LockedUntil != nil:
+		retryAt = *status.LockedUntil
+		reason = "account_locked"
+		message = "too many failed login attempts; the account is temporarily locked, check your email to unlock it"
+	case status.IPFailures >= appPtr.Config.Auth.MaxIPFailures:
+		retryAt = now.Add(appPtr.Config.Auth.LoginFailureWindow)
+		reason = "ip_limit"
+		message = "too many failed login attempts from your network, please try again later"
+	case status.LastFailure != nil && now.Before(status.LastFailure.Add(loginDelay(status.AccountFailures))):
+		retryAt = status.LastFailure.Add(loginDelay(status.AccountFailures))
+		reason = "delay"
+		message = "too many failed login attempts, please wait before trying again"
+	default:
+		return true
+	}
+
+	logSecurityEvent(appPtr, "login_blocked", map[string]string{
+		"email_hash": emailHash(email),
+		"ip":         ip,
+		"reason":     reason,
+	})
+
+	retryAfter := int(math.Ceil(retryAt.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	appPtr.ErrorResponse(w, r, http.StatusTooManyRequests, message)
+
+	return false
+}
+
+// recordLoginFailure counts a failed login against the email and the request's
+// IP address. Once the account reaches the failure limit it is locked and,
+// if it exists, its owner is sent a link to unlock it. user is nil when no
+// account has the email; the email is then only logged as a hash, since it
+// may be a password typed into the wrong field.
+func recordLoginFailure(appPtr *app.Application, r *http.Request, email string, user *data.User, reason string) error {
+	ip := requestIP(r)
+	window := appPtr.Config.Auth.LoginFailureWindow
+
+	failures, err := appPtr.Models.LoginFailures.Record(email, ip, time.Now().Add(-window))
+	if err != nil {
+		return err
+	}
+
+	properties := map[string]string{
+		"email_hash": emailHash(email),
+		"ip":         ip,
+		"reason":     reason,
+		"failures":   strconv.Itoa(failures),
+	}
+	if user != nil {
+		properties["email"] = user.Email
+	}
+
+	logSecurityEvent(appPtr, "login_failed", properties)
+
+	if failures < appPtr.Config.Auth.MaxAccountFailures {
+		return nil
+	}
+
+	lockout := appPtr.Config.Auth.LockoutDuration
+	until := time.Now().Add(lockout)
+
+	// Only the failure that starts the lockout sends the unlock email
+	locked, err := appPtr.Models.LoginFailures.Lock(email, until)
+	if err != nil || !locked {
+		return err
+	}
+
+	properties = map[string]string{
+		"email_hash": emailHash(email),
+		"ip":         ip,
+		"failures":   strconv.Itoa(failures),
+		"until":      until.Format(time.RFC3339),
+	}
+	if user != nil {
+		properties["email"] = user.Email
+	}
+
+	logSecurityEvent(appPtr, "account_locked", properties)
+
+	if user == nil {
+		return nil
+	}
+
+	token, err := appPtr.Models.Tokens.New(user.ID, lockout, data.ScopeUnlock)
+	if err != nil {
+		return err
+	}
+
+	appPtr.WG.Add(1)
+	go func() {
+		defer appPtr.WG.Done()
+
+		emailData := map[string]any{
+			"userName":       user.UserName,
+			"unlockToken":    token.Plaintext,
+			"lockoutMinutes": int(lockout.Minutes()),
+			"frontendURL":    appPtr.Config.FrontendURL,
+		}
+
+		err := appPtr.Mailer.Send(user.Email, "account_unlock", emailData)
+		if err != nil {
+			appPtr.Logger.PrintError(err, nil)
+		}
+	}()
+
+	return nil
+}
+
+// emailHash identifies an email address in the logs without revealing it, so
+// that events for the same address can still be matched up
+func emailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// logSecurityEvent writes a structured log entry for an authentication event
+func logSecurityEvent(appPtr *app.Application, event string, properties map[string]string) {
+	properties["event"] = event
+	appPtr.Logger.PrintInfo(fmt.Sprintf("security event: %s", strings.ReplaceAll(event, "_", " ")), properties)
+}
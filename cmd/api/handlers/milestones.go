@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// ListMilestones lists the milestones of an idea by due date
+func ListMilestones(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		milestones, err := appPtr.Models.Milestones.GetAllForIdea(idea.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"milestones": milestones}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateMilestone adds a milestone to an idea in progress
+func CreateMilestone(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title       string  `json:"title"`
+			Description string  `json:"description"`
+			DueDate     *string `json:"due_date"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		milestone := &data.Milestone{
+			IdeaID:      idea.ID,
+			Title:       input.Title,
+			Description: input.Description,
+			Status:      data.MilestoneStatusOpen,
+		}
+
+		v := validator.New()
+
+		if input.DueDate != nil {
+			milestone.DueDate = readDueDate(v, *input.DueDate)
+		}
+
+		if data.ValidateMilestone(v, milestone); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Milestones.Insert(milestone)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s/milestones/%s", idea.ID, milestone.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"milestone": milestone}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateMilestone edits a milestone or marks it completed. An empty due_date
+// removes the due date.
+func UpdateMilestone(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		milestone, ok := readIdeaMilestone(appPtr, w, r, idea)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title       *string `json:"title"`
+			Description *string `json:"description"`
+			DueDate     *string `json:"due_date"`
+			Status      *string `json:"status"`
+			Version     *int    `json:"version"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Version != nil && *input.Version != milestone.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		if input.Title != nil {
+			milestone.Title = *input.Title
+		}
+		if input.Description != nil {
+			milestone.Description = *input.Description
+		}
+		if input.DueDate != nil {
+			milestone.DueDate = nil
+			if *input.DueDate != "" {
+				milestone.DueDate = readDueDate(v, *input.DueDate)
+			}
+		}
+		if input.Status != nil {
+			milestone.Status = *input.Status
+		}
+
+		if data.ValidateMilestone(v, milestone); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Milestones.Update(milestone)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"milestone": milestone}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteMilestone removes a milestone; its tasks stay on the board
+func DeleteMilestone(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		milestone, ok := readIdeaMilestone(appPtr, w, r, idea)
+		if !ok {
+			return
+		}
+
+		err := appPtr.Models.Milestones.Delete(milestone.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "milestone deleted successfully"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// readTrackedIdea loads the idea in the URL for a change to its milestones or
+// tasks. Only the owner, accepted team members and idea admins may make such
+// changes, and only while the idea is in progress. It writes the error
+// response itself and reports whether the handler should continue.
+func readTrackedIdea(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.Idea, bool) {
+	idea, ok := readVisibleIdea(appPtr, w, r)
+	if !ok {
+		return nil, false
+	}
+
+	user := appPtr.ContextGetUser(r)
+
+	if idea.UserID != user.ID {
+		isMember, err := appPtr.Models.Teams.IsMember(idea.ID, user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return nil, false
+		}
+
+		if !isMember {
+			isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return nil, false
+			}
+			if !isAdmin {
+				appPtr.NotPermittedResponse(w, r)
+				return nil, false
+			}
+		}
+	}
+
+	if idea.Status != data.IdeaStatusInProgress {
+		appPtr.FailedValidationResponse(w, r, map[string]string{"status": "milestones and tasks can only be changed while the idea is in progress"})
+		return nil, false
+	}
+
+	return idea, true
+}
+
+// readIdeaMilestone loads the milestone named in the URL and makes sure it
+// belongs to the idea. It writes the error response itself and reports
+// whether the handler should continue.
+func readIdeaMilestone(appPtr *app.Application, w http.ResponseWriter, r *http.Request, idea *data.Idea) (*data.Milestone, bool) {
+	milestoneID, err := appPtr.ReadUUIDParam(r, "milestone_id")
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	milestone, err := appPtr.Models.Milestones.Get(milestoneID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if milestone.IdeaID != idea.ID {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return milestone, true
+}
+
+// readDueDate parses a due date given as YYYY-MM-DD, recording a validation
+// error if it is malformed.
+func readDueDate(v *validator.Validator, s string) *time.Time {
+	date, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError("due_date", "must be a date in YYYY-MM-DD format")
+		return nil
+	}
+
+	return &date
+}
@@ -7,12 +7,18 @@ import (
     "errors"
     "fmt"
     "net/http"
+    "net/url"
     "time"
 
     "github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
     "github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+    "github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
 )
 
+// oauthLoginCodeTTL is how long the frontend has to exchange the code it is
+// redirected with after signing in with Google
+const oauthLoginCodeTTL = time.Minute
+
 // GoogleLogin initiates Google OAuth login
 func GoogleLogin(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
@@ -70,17 +76,16 @@ func GoogleCallback(appPtr *app.Application) http.HandlerFunc {
 
         existingUser, err := appPtr.Models.Users.GetByEmail(googleUser.Email)
         if err == nil {
-            authToken, err := appPtr.Models.Tokens.New(existingUser.ID, 24*time.Hour, data.ScopeAuthentication)
+            redirectURL, err := oauthCallbackURL(appPtr, existingUser)
             if err != nil {
-                appPtr.Logger.PrintError(err, map[string]string{"message": "Failed to generate authentication token"})
+                appPtr.Logger.PrintError(err, map[string]string{"message": "Failed to generate login code"})
                 appPtr.ServerErrorResponse(w, r, err)
                 return
             }
 
-            redirectURL := fmt.Sprintf("%s/auth/callback?token=%s", appPtr.Config.FrontendURL, authToken.Plaintext)
             appPtr.Logger.PrintInfo("Redirecting existing user to frontend", map[string]string{
                 "email": existingUser.Email,
-                "url":   redirectURL,
+                "url":   appPtr.Config.FrontendURL + "/auth/callback",
             })
             http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
             return
@@ -103,22 +108,109 @@ func GoogleCallback(appPtr *app.Application) http.HandlerFunc {
             return
         }
 
-        // Generate authentication token
-        authToken, err := appPtr.Models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+        redirectURL, err := oauthCallbackURL(appPtr, user)
         if err != nil {
-            appPtr.Logger.PrintError(err, map[string]string{"message": "Failed to generate authentication token"})
+            appPtr.Logger.PrintError(err, map[string]string{"message": "Failed to generate login code"})
             appPtr.ServerErrorResponse(w, r, err)
             return
         }
 
-        redirectURL := fmt.Sprintf("%s/auth/callback?token=%s", appPtr.Config.FrontendURL, authToken.Plaintext)
         appPtr.Logger.PrintInfo("Redirecting to frontend", map[string]string{
-            "url": redirectURL,
+            "url": appPtr.Config.FrontendURL + "/auth/callback",
         })
         http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
     }
 }
 
+// CreateOAuthAuthenticationToken exchanges the one-time code from the OAuth
+// callback for session tokens. Like CreateAuthenticationToken, it returns a
+// two-factor challenge instead when the user needs a second step.
+func CreateOAuthAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var input struct {
+            Code string `json:"code"`
+        }
+
+        err := appPtr.ReadJSON(w, r, &input)
+        if err != nil {
+            appPtr.BadRequestResponse(w, r, err)
+            return
+        }
+
+        v := validator.New()
+
+        if data.ValidateTokenPlaintext(v, input.Code); !v.Valid() {
+            appPtr.FailedValidationResponse(w, r, map[string]string{"code": v.Errors["token"]})
+            return
+        }
+
+        // The code can only be exchanged once, so it is deleted as it is
+        // looked up
+        userID, err := appPtr.Models.Tokens.Consume(data.ScopeOAuthLogin, input.Code)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                appPtr.FailedValidationResponse(w, r, map[string]string{"code": "invalid or expired login code"})
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        user, err := appPtr.Models.Users.Get(userID)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                appPtr.FailedValidationResponse(w, r, map[string]string{"code": "invalid or expired login code"})
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        challenge, setupRequired, err := startTwoFactorChallenge(appPtr, user)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        if challenge != nil {
+            env := app.Envelope{"two_factor_token": challenge, "two_factor_setup_required": setupRequired}
+
+            err = appPtr.WriteJSON(w, http.StatusAccepted, env, nil)
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        tokens, err := startSession(appPtr, r, user)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusCreated, sessionEnvelope(tokens), nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
+// oauthCallbackURL returns the frontend URL a user is sent back to after
+// signing in with Google. It carries a short-lived one-time code rather than
+// the session tokens, which would otherwise end up in browser history,
+// proxy logs and Referer headers; the frontend exchanges the code at
+// CreateOAuthAuthenticationToken.
+func oauthCallbackURL(appPtr *app.Application, user *data.User) (string, error) {
+    code, err := appPtr.Models.Tokens.New(user.ID, oauthLoginCodeTTL, data.ScopeOAuthLogin)
+    if err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("%s/auth/callback?code=%s", appPtr.Config.FrontendURL, url.QueryEscape(code.Plaintext)), nil
+}
+
 func generateStateOauthCookie(w http.ResponseWriter) string {
     expiration := time.Now().Add(365 * 24 * time.Hour)
     b := make([]byte, 16)
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// ListRecommendedIdeas suggests open ideas that fit the caller's skills,
+// program, faculty and year of study, or resemble ideas they proposed. Each
+// suggestion lists the reasons it was made.
+func ListRecommendedIdeas(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = "-score"
+		filters.SortSafelist = []string{"-score"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		recommendations, metadata, err := appPtr.Models.Ideas.GetRecommended(user.ID, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		ideas := make([]*data.Idea, len(recommendations))
+		for i, rec := range recommendations {
+			ideas[i] = rec.Idea
+		}
+
+		err = setUserVotes(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = setUserBookmarks(appPtr, user, ideas)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "recommendations": recommendations}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListIdeaRevisions lists the earlier versions of an idea, newest first
+func ListIdeaRevisions(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		revisions, err := appPtr.Models.Revisions.GetAllForIdea(idea.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		revisions = append([]*data.IdeaRevision{data.CurrentRevision(idea)}, revisions...)
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"revisions": revisions}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ShowIdeaRevision returns an idea as it was at a given version
+func ShowIdeaRevision(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		version, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("version"))
+		if err != nil || version < 1 {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		revision, err := appPtr.Models.Revisions.Get(idea, version)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"revision": revision}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DiffIdeaRevisions returns the field-level changes between two versions of an
+// idea given by the from and to query parameters. to defaults to the current version.
+func DiffIdeaRevisions(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		from := appPtr.ReadInt(qs, "from", 0, v)
+		to := appPtr.ReadInt(qs, "to", idea.Version, v)
+
+		v.Check(from > 0, "from", "must be provided")
+		v.Check(from <= idea.Version, "from", "must not be greater than the current version")
+		v.Check(to > 0, "to", "must be greater than zero")
+		v.Check(to <= idea.Version, "to", "must not be greater than the current version")
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		fromRevision, err := appPtr.Models.Revisions.Get(idea, from)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		toRevision, err := appPtr.Models.Revisions.Get(idea, to)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		env := app.Envelope{
+			"from":    from,
+			"to":      to,
+			"changes": data.DiffRevisions(fromRevision, toRevision),
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RestoreIdeaRevision brings back the content of an earlier version as a new version.
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func RestoreIdeaRevision(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea := appPtr.ContextGetIdea(r)
+
+		version, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("version"))
+		if err != nil || version < 1 {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		if version == idea.Version {
+			appPtr.FailedValidationResponse(w, r, map[string]string{"version": "is already the current version"})
+			return
+		}
+
+		revision, err := appPtr.Models.Revisions.Get(idea, version)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		revision.Restore(idea)
+
+		categoryExists, err := appPtr.Models.Categories.Exists(idea.Category)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateIdea(v, idea, categoryExists); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Ideas.Update(idea)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/jwt"
+	"github.com/google/uuid"
+)
+
+// maxUserAgentLength caps the user agent stored with a session
+const maxUserAgentLength = 512
+
+// errTwoFactorSetupRequired is returned by refreshSession when the user must
+// use two-factor authentication but has not set it up. The session is ended,
+// so the user has to log in again and enrol.
+var errTwoFactorSetupRequired = errors.New("two-factor authentication setup required")
+
+// ListSessions lists the caller's active sessions, most recently used first
+func ListSessions(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := appPtr.ContextGetUser(r)
+
+		sessions, err := appPtr.Models.Tokens.GetSessionsForUser(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		currentID := appPtr.ContextGetSessionID(r)
+		for _, session := range sessions {
+			session.Current = session.ID == currentID
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"sessions": sessions}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RevokeSession signs the caller out of one of their sessions
+func RevokeSession(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		err = appPtr.Models.Tokens.DeleteSession(id, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.RevokeSignedTokens(id)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "session revoked successfully"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RevokeAllSessions signs the caller out everywhere, including the session
+// the request was made with
+func RevokeAllSessions(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := appPtr.ContextGetUser(r)
+
+		sessions, err := appPtr.Models.Tokens.GetSessionsForUser(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		for _, scope := range []string{data.ScopeAuthentication, data.ScopeRefresh} {
+			err := appPtr.Models.Tokens.DeleteAllForUser(scope, user.ID)
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		sessionIDs := []uuid.UUID{appPtr.ContextGetSessionID(r)}
+		for _, session := range sessions {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+
+		err = appPtr.RevokeSignedTokens(sessionIDs...)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "signed out of all sessions"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// requestIP returns the address of the client that made the request
+func requestIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// sessionUserAgent returns the request's user agent, shortened to fit a
+// session record
+func sessionUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		// Cutting by bytes can split a character in two, which is dropped
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return userAgent
+}
+
+// startSession issues the authentication and refresh tokens for a login from
+// the device that made the request
+func startSession(appPtr *app.Application, r *http.Request, user *data.User) (*data.SessionTokens, error) {
+	tokens, err := appPtr.Models.Tokens.NewSession(
+		user.ID,
+		opaqueAccessTTL(appPtr),
+		appPtr.Config.Auth.RefreshTokenTTL,
+		sessionUserAgent(r),
+		requestIP(r),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, signAccessToken(appPtr, user, tokens)
+}
+
+// refreshSession exchanges a refresh token for new session tokens. When the
+// token turns out to be replayed, the signed tokens of the revoked session
+// are rejected as well. A session of a user who is required to use
+// two-factor authentication but has not enabled it cannot be refreshed.
+func refreshSession(appPtr *app.Application, r *http.Request, refreshToken string) (*data.SessionTokens, error) {
+	tokens, err := appPtr.Models.Tokens.RotateRefreshToken(
+		refreshToken,
+		opaqueAccessTTL(appPtr),
+		appPtr.Config.Auth.RefreshTokenTTL,
+		sessionUserAgent(r),
+		requestIP(r),
+	)
+	if errors.Is(err, data.ErrRefreshTokenReused) {
+		if revokeErr := appPtr.RevokeSignedTokens(tokens.SessionID); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, err
+	}
+	if err != nil || (appPtr.Signer == nil && !appPtr.Config.Auth.RequireAdminTwoFactor) {
+		return tokens, err
+	}
+
+	user, err := appPtr.Models.Users.Get(tokens.Refresh.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactorRequired(appPtr, user) {
+		tf, err := getTwoFactor(appPtr, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if !tf.Enabled() {
+			err = appPtr.Models.Tokens.DeleteSession(tokens.SessionID, user.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			err = appPtr.RevokeSignedTokens(tokens.SessionID)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, errTwoFactorSetupRequired
+		}
+	}
+
+	return tokens, signAccessToken(appPtr, user, tokens)
+}
+
+// endSessions signs the user out of all their sessions except keep, which is
+// uuid.Nil to end every one of them
+func endSessions(appPtr *app.Application, userID, keep uuid.UUID) error {
+	sessions, err := appPtr.Models.Tokens.GetSessionsForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	var sessionIDs []uuid.UUID
+
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+
+		err := appPtr.Models.Tokens.DeleteSession(session.ID, userID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	return appPtr.RevokeSignedTokens(sessionIDs...)
+}
+
+// opaqueAccessTTL is the lifetime of the access tokens kept in the database,
+// or zero when access tokens are signed instead
+func opaqueAccessTTL(appPtr *app.Application) time.Duration {
+	if appPtr.Signer != nil {
+		return 0
+	}
+	return appPtr.Config.Auth.AccessTokenTTL
+}
+
+// signAccessToken adds a signed access token to the session tokens when
+// signed tokens are enabled. It carries the user's activation status and
+// permissions, so that requests made with it need no database lookup.
+func signAccessToken(appPtr *app.Application, user *data.User, tokens *data.SessionTokens) error {
+	if appPtr.Signer == nil {
+		return nil
+	}
+
+	permissions, err := appPtr.Models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiry := now.Add(appPtr.Config.Auth.AccessTokenTTL)
+
+	plaintext, err := appPtr.Signer.Sign(jwt.Claims{
+		ID:          uuid.NewString(),
+		Subject:     user.ID.String(),
+		SessionID:   tokens.SessionID.String(),
+		Activated:   user.Activated,
+		Permissions: append([]string{}, permissions...),
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   expiry.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	tokens.Access = &data.Token{
+		Plaintext: plaintext,
+		UserID:    user.ID,
+		Expiry:    expiry,
+		Scope:     data.ScopeAuthentication,
+	}
+
+	return nil
+}
+
+// sessionEnvelope wraps a session's tokens for the response to a login or
+// refresh
+func sessionEnvelope(tokens *data.SessionTokens) app.Envelope {
+	return app.Envelope{"authentication_token": tokens.Access, "refresh_token": tokens.Refresh}
+}
+
+// readCurrentUser loads the caller's account. Signed access tokens only carry
+// the user's ID, activation status and permissions, so handlers that need
+// anything else read the account with this. It writes the error response
+// itself and reports whether the handler should continue.
+func readCurrentUser(appPtr *app.Application, w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	user, err := appPtr.Models.Users.Get(appPtr.ContextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.InvalidAuthenticationTokenResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ListTasks shows an idea's task board, one column per task status. The
+// assignee_id and milestone_id parameters narrow it down to matching tasks.
+func ListTasks(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var filter data.TaskFilter
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filter.AssigneeID = appPtr.ReadUUID(qs, "assignee_id", v)
+		filter.MilestoneID = appPtr.ReadUUID(qs, "milestone_id", v)
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		board, err := appPtr.Models.Tasks.GetBoard(idea.ID, filter)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"board": board}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateTask adds a task to the bottom of a column of an idea's board. The
+// assignee may be any user of the platform.
+func CreateTask(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			MilestoneID *uuid.UUID `json:"milestone_id"`
+			AssigneeID  *uuid.UUID `json:"assignee_id"`
+			Status      string     `json:"status"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		task := &data.Task{
+			IdeaID:      idea.ID,
+			MilestoneID: input.MilestoneID,
+			Title:       input.Title,
+			Description: input.Description,
+			AssigneeID:  input.AssigneeID,
+			Status:      input.Status,
+			CreatedBy:   &user.ID,
+		}
+
+		if task.Status == "" {
+			task.Status = data.TaskStatusTodo
+		}
+
+		v := validator.New()
+
+		err = checkTaskReferences(appPtr, v, task)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		if data.ValidateTask(v, task); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Tasks.Insert(task)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s/tasks/%s", idea.ID, task.ID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"task": task}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateTask edits a task or moves it on the board. A new status without a
+// position moves the task to the bottom of that column. An empty
+// milestone_id or assignee_id clears it.
+func UpdateTask(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		task, ok := readIdeaTask(appPtr, w, r, idea)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title       *string `json:"title"`
+			Description *string `json:"description"`
+			MilestoneID *string `json:"milestone_id"`
+			AssigneeID  *string `json:"assignee_id"`
+			Status      *string `json:"status"`
+			Position    *int    `json:"position"`
+			Version     *int    `json:"version"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Version != nil && *input.Version != task.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		if input.Title != nil {
+			task.Title = *input.Title
+		}
+		if input.Description != nil {
+			task.Description = *input.Description
+		}
+		if input.MilestoneID != nil {
+			task.MilestoneID = readOptionalUUID(v, "milestone_id", *input.MilestoneID)
+		}
+		if input.AssigneeID != nil {
+			task.AssigneeID = readOptionalUUID(v, "assignee_id", *input.AssigneeID)
+		}
+		if input.Status != nil && *input.Status != task.Status {
+			task.Status = *input.Status
+			task.Position = data.TaskPositionEnd
+		}
+		if input.Position != nil {
+			task.Position = *input.Position
+		}
+
+		err = checkTaskReferences(appPtr, v, task)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		if data.ValidateTask(v, task); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Tasks.Update(task)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		// The assignee's name is read along with the task
+		task, err = appPtr.Models.Tasks.Get(task.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"task": task}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteTask removes a task from an idea's board
+func DeleteTask(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readTrackedIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		task, ok := readIdeaTask(appPtr, w, r, idea)
+		if !ok {
+			return
+		}
+
+		err := appPtr.Models.Tasks.Delete(task)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "task deleted successfully"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// readIdeaTask loads the task named in the URL and makes sure it belongs to
+// the idea. It writes the error response itself and reports whether the
+// handler should continue.
+func readIdeaTask(appPtr *app.Application, w http.ResponseWriter, r *http.Request, idea *data.Idea) (*data.Task, bool) {
+	taskID, err := appPtr.ReadUUIDParam(r, "task_id")
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	task, err := appPtr.Models.Tasks.Get(taskID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if task.IdeaID != idea.ID {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return task, true
+}
+
+// checkTaskReferences records validation errors unless the task's milestone
+// belongs to the same idea and its assignee is an existing user.
+func checkTaskReferences(appPtr *app.Application, v *validator.Validator, task *data.Task) error {
+	if task.MilestoneID != nil {
+		milestone, err := appPtr.Models.Milestones.Get(*task.MilestoneID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+		v.Check(milestone != nil && milestone.IdeaID == task.IdeaID, "milestone_id", "must reference a milestone of this idea")
+	}
+
+	if task.AssigneeID != nil {
+		_, err := appPtr.Models.Users.Get(*task.AssigneeID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+		v.Check(err == nil, "assignee_id", "must reference an existing user")
+	}
+
+	return nil
+}
+
+// readOptionalUUID parses a UUID given in a request body, where an empty
+// string stands for none.
+func readOptionalUUID(v *validator.Validator, key, s string) *uuid.UUID {
+	if s == "" {
+		return nil
+	}
+
+	id, err := uuid.Parse(s)
+	if err != nil {
+		v.AddError(key, "must be a valid UUID")
+		return nil
+	}
+
+	return &id
+}
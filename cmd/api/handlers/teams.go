@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ListIdeaTeam lists the members of an idea's team. Only the owner sees
+// outstanding and declined invitations.
+func ListIdeaTeam(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea, ok := readVisibleIdea(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		statuses := []string{data.TeamStatusAccepted}
+		if idea.UserID == appPtr.ContextGetUser(r).ID {
+			statuses = []string{}
+		}
+
+		members, err := appPtr.Models.Teams.GetAllForIdea(idea.ID, statuses)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		env := app.Envelope{
+			"owner_id": idea.UserID,
+			"max_size": appPtr.Config.Teams.MaxSize,
+			"members":  members,
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// InviteTeamMember invites a user to join an idea's team with the given role.
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func InviteTeamMember(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea := appPtr.ContextGetIdea(r)
+		user := appPtr.ContextGetUser(r)
+
+		var input struct {
+			UserID uuid.UUID `json:"user_id"`
+			Role   string    `json:"role"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		member := &data.TeamMember{
+			IdeaID:    idea.ID,
+			UserID:    input.UserID,
+			Role:      input.Role,
+			InvitedBy: &user.ID,
+		}
+
+		v := validator.New()
+
+		data.ValidateTeamMember(v, member)
+		v.Check(member.UserID != idea.UserID, "user_id", "the owner is already on the team")
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Teams.Invite(member, appPtr.Config.Teams.MaxSize)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("user_id", "no matching user found")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrDuplicateTeamMember):
+				v.AddError("user_id", "this user is already on the team or has been invited")
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrTeamFull):
+				v.AddError("team", fmt.Sprintf("must not have more than %d members", appPtr.Config.Teams.MaxSize))
+				appPtr.FailedValidationResponse(w, r, v.Errors)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		member, err = appPtr.Models.Teams.Get(idea.ID, member.UserID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/ideas/%s/team/%s", idea.ID, member.UserID))
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"member": member}, headers)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// UpdateTeamMember changes a team member's role.
+// The idea is loaded and ownership checked by middleware.RequireIdeaOwner
+func UpdateTeamMember(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idea := appPtr.ContextGetIdea(r)
+
+		member, ok := readTeamMember(appPtr, w, r, idea.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Role    *string `json:"role"`
+			Version *int    `json:"version"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Version != nil && *input.Version != member.Version {
+			appPtr.EditConflictResponse(w, r)
+			return
+		}
+
+		if input.Role != nil {
+			member.Role = *input.Role
+		}
+
+		v := validator.New()
+
+		if data.ValidateTeamMember(v, member); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = appPtr.Models.Teams.Update(member)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"member": member}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RemoveTeamMember removes a member or withdraws an invitation. The idea's
+// owner may remove anyone; members may remove themselves to leave the team.
+func RemoveTeamMember(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ideaID, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		idea, err := appPtr.Models.Ideas.Get(ideaID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		member, ok := readTeamMember(appPtr, w, r, idea.ID)
+		if !ok {
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if user.ID != idea.UserID && user.ID != member.UserID {
+			appPtr.NotPermittedResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.Teams.Delete(idea.ID, member.UserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "team member successfully removed"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RespondToTeamInvitation accepts or declines the caller's invitation to an idea's team
+func RespondToTeamInvitation(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ideaID, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		member, err := appPtr.Models.Teams.Get(ideaID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		var input struct {
+			Status string `json:"status"`
+		}
+
+		err = appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		v.Check(validator.PermittedValue(input.Status, data.TeamStatusAccepted, data.TeamStatusDeclined), "status", "must be accepted or declined")
+		v.Check(member.Status == data.TeamStatusInvited, "status", "this invitation has already been answered")
+
+		if !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		now := time.Now()
+		member.Status = input.Status
+		member.RespondedAt = &now
+
+		err = appPtr.Models.Teams.Update(member)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				appPtr.EditConflictResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"member": member}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListTeamInvitations lists the caller's unanswered team invitations
+func ListTeamInvitations(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invitations, err := appPtr.Models.Teams.GetInvitationsForUser(appPtr.ContextGetUser(r).ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"invitations": invitations}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ListUserTeams lists the ideas a user owns or has joined. Other users only
+// see ideas that have passed moderation.
+func ListUserTeams(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		canModerate, err := appPtr.UserHasPermission(r, "ideas:moderate")
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		statuses := data.PublicIdeaStatuses
+		if canModerate || user.ID == userID {
+			statuses = []string{}
+		}
+
+		teams, err := appPtr.Models.Teams.GetTeamsForUser(userID, statuses)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"teams": teams}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// readTeamMember loads the member named by the :user_id URL parameter from
+// the idea's team, writing a 404 if there is none.
+func readTeamMember(appPtr *app.Application, w http.ResponseWriter, r *http.Request, ideaID uuid.UUID) (*data.TeamMember, bool) {
+	userID, err := appPtr.ReadUUIDParam(r, "user_id")
+	if err != nil {
+		appPtr.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	member, err := appPtr.Models.Teams.Get(ideaID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return member, true
+}
@@ -11,7 +11,12 @@ import (
     "github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
 )
 
-// CreateAuthenticationToken creates an authentication token for login
+// CreateAuthenticationToken creates an authentication token for login. When
+// the user has two-factor authentication, it returns a challenge token for
+// CreateTwoFactorAuthenticationToken instead. Failed attempts slow down and
+// eventually lock out further attempts, see checkLoginAllowed. They are only
+// forgotten once a session is issued, so a correct password alone does not
+// reset the count.
 func CreateAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         var input struct {
@@ -34,10 +39,19 @@ func CreateAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
             return
         }
 
+        if !checkLoginAllowed(appPtr, w, r, input.Email) {
+            return
+        }
+
         user, err := appPtr.Models.Users.GetByEmail(input.Email)
         if err != nil {
             switch {
             case errors.Is(err, data.ErrRecordNotFound):
+                err = recordLoginFailure(appPtr, r, input.Email, nil, "unknown_email")
+                if err != nil {
+                    appPtr.ServerErrorResponse(w, r, err)
+                    return
+                }
                 appPtr.InvalidCredentialsResponse(w, r)
             default:
                 appPtr.ServerErrorResponse(w, r, err)
@@ -52,17 +66,118 @@ func CreateAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
         }
 
         if !match {
+            err = recordLoginFailure(appPtr, r, input.Email, user, "wrong_password")
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+                return
+            }
             appPtr.InvalidCredentialsResponse(w, r)
             return
         }
 
-        token, err := appPtr.Models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+        challenge, setupRequired, err := startTwoFactorChallenge(appPtr, user)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        if challenge != nil {
+            env := app.Envelope{"two_factor_token": challenge, "two_factor_setup_required": setupRequired}
+
+            err = appPtr.WriteJSON(w, http.StatusAccepted, env, nil)
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        tokens, err := startSession(appPtr, r, user)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.Models.LoginFailures.Clear(user.Email)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusCreated, sessionEnvelope(tokens), nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
+// RefreshAuthenticationToken exchanges a refresh token for a new
+// authentication token and refresh token. Each refresh token can be used
+// once; replaying one signs the session out.
+func RefreshAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var input struct {
+            RefreshToken string `json:"refresh_token"`
+        }
+
+        err := appPtr.ReadJSON(w, r, &input)
+        if err != nil {
+            appPtr.BadRequestResponse(w, r, err)
+            return
+        }
+
+        v := validator.New()
+
+        if data.ValidateTokenPlaintext(v, input.RefreshToken); !v.Valid() {
+            appPtr.FailedValidationResponse(w, r, v.Errors)
+            return
+        }
+
+        tokens, err := refreshSession(appPtr, r, input.RefreshToken)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                appPtr.InvalidAuthenticationTokenResponse(w, r)
+            case errors.Is(err, data.ErrRefreshTokenReused):
+                appPtr.Logger.PrintInfo("refresh token reused, session revoked", map[string]string{"ip": requestIP(r)})
+                appPtr.InvalidAuthenticationTokenResponse(w, r)
+            case errors.Is(err, errTwoFactorSetupRequired):
+                appPtr.ErrorResponse(w, r, http.StatusUnauthorized, "two-factor authentication must be set up, please log in again")
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusCreated, sessionEnvelope(tokens), nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
+// DeleteAuthenticationToken logs out by revoking the token the request was
+// authenticated with
+func DeleteAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        user := appPtr.ContextGetUser(r)
+
+        sessionID := appPtr.ContextGetSessionID(r)
+
+        err := appPtr.Models.Tokens.DeleteSession(sessionID, user.ID)
+        if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        // A signed token stays valid after its session's refresh token is
+        // gone, so the session is denied until the token expires
+        err = appPtr.RevokeSignedTokens(sessionID)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
             return
         }
 
-        err = appPtr.WriteJSON(w, http.StatusCreated, app.Envelope{"authentication_token": token}, nil)
+        err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "logged out successfully"}, nil)
         if err != nil {
             appPtr.ServerErrorResponse(w, r, err)
         }
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// ListTrash lists the ideas the caller has deleted, most recently deleted
+// first, and their profile if it is deleted too. Each item can be restored
+// until its purge_at, after which it is removed for good.
+func ListTrash(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = "-deleted_at"
+		filters.SortSafelist = []string{"-deleted_at"}
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+		retention := appPtr.Config.Trash.Retention
+
+		ideas, metadata, err := appPtr.Models.Ideas.GetTrash(user.ID, retention, filters)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		var trashedProfile *data.TrashedProfile
+
+		profile, err := appPtr.Models.UserProfile.GetDeleted(user.ID, retention)
+		switch {
+		case err == nil:
+			trashedProfile = &data.TrashedProfile{Profile: profile, PurgeAt: profile.DeletedAt.Add(retention)}
+		case !errors.Is(err, data.ErrRecordNotFound):
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "ideas": ideas, "profile": trashedProfile}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RestoreIdea takes a deleted idea out of the trash. Only its owner or an
+// idea admin may restore it, and only within the retention window.
+func RestoreIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		idea, err := appPtr.Models.Ideas.GetDeleted(id, appPtr.Config.Trash.Retention)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if idea.UserID != user.ID {
+			isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+			if !isAdmin {
+				appPtr.NotPermittedResponse(w, r)
+				return
+			}
+		}
+
+		err = appPtr.Models.Ideas.Restore(idea)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
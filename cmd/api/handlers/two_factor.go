@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/totp"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+)
+
+// twoFactorChallengeTTL is how long a user has to enter their code after
+// giving their password
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// ShowTwoFactor reports whether the caller has two-factor authentication
+// enabled or is required to, and how many recovery codes they have left
+func ShowTwoFactor(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := readCurrentUser(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		tf, err := getTwoFactor(appPtr, user)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		remaining := 0
+		if tf.Enabled() {
+			remaining, err = appPtr.Models.TwoFactor.RemainingRecoveryCodes(user.ID)
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		env := app.Envelope{"two_factor": map[string]any{
+			"enabled":                  tf.Enabled(),
+			"required":                 twoFactorRequired(appPtr, user),
+			"recovery_codes_remaining": remaining,
+		}}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// EnrolTwoFactor starts setting up two-factor authentication. It returns a
+// new secret and the otpauth:// URI for authenticator apps; nothing changes
+// at login until the secret is confirmed with ConfirmTwoFactor.
+func EnrolTwoFactor(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := readCurrentUser(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		env, err := startTwoFactorEnrolment(appPtr, user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrTwoFactorEnabled):
+				appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "is already enabled"})
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves
+// their authenticator works by sending a first code. The response holds the
+// recovery codes, which are not shown again. The user's other sessions are
+// ended, since they were started without a second factor.
+func ConfirmTwoFactor(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Code string `json:"code"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateTwoFactorCode(v, input.Code, ""); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		codes, ok := confirmTwoFactor(appPtr, w, r, user, input.Code)
+		if !ok {
+			return
+		}
+
+		env := app.Envelope{"message": "two-factor authentication enabled", "recovery_codes": codes}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a code
+// or recovery code. Admins cannot turn it off while it is required for them.
+func DisableTwoFactor(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateTwoFactorCode(v, input.Code, input.RecoveryCode); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user, ok := readCurrentUser(appPtr, w, r)
+		if !ok {
+			return
+		}
+
+		if twoFactorRequired(appPtr, user) {
+			appPtr.ErrorResponse(w, r, http.StatusForbidden, "two-factor authentication is required for admin accounts")
+			return
+		}
+
+		if !checkSecondFactor(appPtr, w, r, user, input.Code, input.RecoveryCode) {
+			return
+		}
+
+		err = appPtr.Models.TwoFactor.Delete(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "two-factor authentication disabled"}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes after checking
+// a code from their authenticator
+func RegenerateRecoveryCodes(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Code string `json:"code"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateTwoFactorCode(v, input.Code, ""); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if !checkSecondFactor(appPtr, w, r, user, input.Code, "") {
+			return
+		}
+
+		codes, err := appPtr.Models.TwoFactor.RegenerateRecoveryCodes(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"recovery_codes": codes}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// EnrolTwoFactorAtLogin lets a user who must set up two-factor authentication
+// before logging in, such as an admin when it is required, get a secret
+// using the challenge token they were given instead of a session.
+func EnrolTwoFactorAtLogin(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			TwoFactorToken string `json:"two_factor_token"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		user, ok := readTwoFactorChallenge(appPtr, w, r, input.TwoFactorToken)
+		if !ok {
+			return
+		}
+
+		env, err := startTwoFactorEnrolment(appPtr, user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrTwoFactorEnabled):
+				appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "is already enabled"})
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateTwoFactorAuthenticationToken completes a login that needs a second
+// step. It takes the challenge token from CreateAuthenticationToken and a
+// code or recovery code, and returns the session tokens. A user still setting
+// up two-factor authentication confirms it with their first code here and
+// also gets their recovery codes. A wrong code uses up the challenge, so the
+// user has to give their password again, and counts as a failed login.
+func CreateTwoFactorAuthenticationToken(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			TwoFactorToken string `json:"two_factor_token"`
+			Code           string `json:"code"`
+			RecoveryCode   string `json:"recovery_code"`
+		}
+
+		err := appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateTwoFactorCode(v, input.Code, input.RecoveryCode); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user, ok := readTwoFactorChallenge(appPtr, w, r, input.TwoFactorToken)
+		if !ok {
+			return
+		}
+
+		// The challenge can only be answered once
+		err = appPtr.Models.Tokens.DeleteAllForUser(data.ScopeTwoFactor, user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		tf, err := getTwoFactor(appPtr, user)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		var recoveryCodes []string
+
+		switch {
+		case tf.Enabled():
+			if !checkSecondFactor(appPtr, w, r, user, input.Code, input.RecoveryCode) {
+				return
+			}
+		case tf != nil && input.Code != "":
+			recoveryCodes, ok = confirmTwoFactor(appPtr, w, r, user, input.Code)
+			if !ok {
+				return
+			}
+		default:
+			appPtr.InvalidCredentialsResponse(w, r)
+			return
+		}
+
+		tokens, err := startSession(appPtr, r, user)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.Models.LoginFailures.Clear(user.Email)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		env := sessionEnvelope(tokens)
+		if recoveryCodes != nil {
+			env["recovery_codes"] = recoveryCodes
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusCreated, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// startTwoFactorChallenge decides whether a user who has given their password
+// needs a second step to log in. If so it returns a short-lived challenge
+// token to exchange at CreateTwoFactorAuthenticationToken, and whether the
+// user must first set two-factor authentication up. It returns a nil token
+// when the password is enough.
+func startTwoFactorChallenge(appPtr *app.Application, user *data.User) (*data.Token, bool, error) {
+	tf, err := getTwoFactor(appPtr, user)
+	if err != nil {
+		return nil, false, err
+	}
+
+	setupRequired := !tf.Enabled() && twoFactorRequired(appPtr, user)
+
+	if !tf.Enabled() && !setupRequired {
+		return nil, false, nil
+	}
+
+	token, err := appPtr.Models.Tokens.New(user.ID, twoFactorChallengeTTL, data.ScopeTwoFactor)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return token, setupRequired, nil
+}
+
+// twoFactorRequired reports whether the configuration forces the user to use
+// two-factor authentication
+func twoFactorRequired(appPtr *app.Application, user *data.User) bool {
+	return appPtr.Config.Auth.RequireAdminTwoFactor && user.UserType == "admin"
+}
+
+// getTwoFactor returns the user's enrolment, or nil if they never started one
+func getTwoFactor(appPtr *app.Application, user *data.User) (*data.TwoFactor, error) {
+	tf, err := appPtr.Models.TwoFactor.Get(user.ID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return tf, err
+}
+
+// startTwoFactorEnrolment stores a new pending secret for the user and
+// returns it along with its otpauth:// URI
+func startTwoFactorEnrolment(appPtr *app.Application, user *data.User) (app.Envelope, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = appPtr.Models.TwoFactor.SetPending(user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.Envelope{"two_factor": map[string]string{
+		"secret":      secret,
+		"otpauth_uri": totp.KeyURI(appPtr.Config.Auth.TwoFactorIssuer, user.Email, secret),
+	}}, nil
+}
+
+// confirmTwoFactor enables the user's pending enrolment if the code matches
+// its secret and returns the new recovery codes. Every session other than
+// the one the request was made with is ended. It writes the error response
+// itself and reports whether the handler should continue.
+func confirmTwoFactor(appPtr *app.Application, w http.ResponseWriter, r *http.Request, user *data.User, code string) ([]string, bool) {
+	tf, err := appPtr.Models.TwoFactor.Get(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "enrolment has not been started"})
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if tf.Enabled() {
+		appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "is already enabled"})
+		return nil, false
+	}
+
+	if !checkTOTP(appPtr, w, r, user, tf, code) {
+		return nil, false
+	}
+
+	codes, err := appPtr.Models.TwoFactor.Enable(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTwoFactorEnabled):
+			appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "is already enabled"})
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	err = endSessions(appPtr, user.ID, appPtr.ContextGetSessionID(r))
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	return codes, true
+}
+
+// checkSecondFactor checks a code against the user's enabled enrolment, or
+// spends a recovery code. Wrong codes count as failed logins, so guessing is
+// slowed down and locked out like guessing a password. It writes the error
+// response itself and reports whether the handler should continue.
+func checkSecondFactor(appPtr *app.Application, w http.ResponseWriter, r *http.Request, user *data.User, code, recoveryCode string) bool {
+	tf, err := getTwoFactor(appPtr, user)
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+		return false
+	}
+
+	if !tf.Enabled() {
+		appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor": "is not enabled"})
+		return false
+	}
+
+	if recoveryCode != "" {
+		if !checkLoginAllowed(appPtr, w, r, user.Email) {
+			return false
+		}
+
+		ok, err := appPtr.Models.TwoFactor.UseRecoveryCode(user.ID, recoveryCode)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return false
+		}
+		if !ok {
+			err = recordLoginFailure(appPtr, r, user.Email, user, "wrong_recovery_code")
+			if err != nil {
+				appPtr.ServerErrorResponse(w, r, err)
+				return false
+			}
+			appPtr.FailedValidationResponse(w, r, map[string]string{"recovery_code": "is invalid or already used"})
+			return false
+		}
+		return true
+	}
+
+	return checkTOTP(appPtr, w, r, user, tf, code)
+}
+
+// checkTOTP checks a code against the enrolment's secret and makes sure the
+// same code is not accepted twice. A wrong code counts as a failed login. It
+// writes the error response itself and reports whether the handler should
+// continue.
+func checkTOTP(appPtr *app.Application, w http.ResponseWriter, r *http.Request, user *data.User, tf *data.TwoFactor, code string) bool {
+	if !checkLoginAllowed(appPtr, w, r, user.Email) {
+		return false
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, time.Now())
+	if ok {
+		var err error
+
+		ok, err = appPtr.Models.TwoFactor.UseStep(tf.UserID, step)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return false
+		}
+	}
+
+	if !ok {
+		err := recordLoginFailure(appPtr, r, user.Email, user, "wrong_two_factor_code")
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return false
+		}
+		appPtr.FailedValidationResponse(w, r, map[string]string{"code": "is invalid or expired"})
+		return false
+	}
+
+	return true
+}
+
+// readTwoFactorChallenge loads the user a two-factor challenge token was
+// issued to. It writes the error response itself and reports whether the
+// handler should continue.
+func readTwoFactorChallenge(appPtr *app.Application, w http.ResponseWriter, r *http.Request, token string) (*data.User, bool) {
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor_token": v.Errors["token"]})
+		return nil, false
+	}
+
+	user, err := appPtr.Models.Users.GetForToken(data.ScopeTwoFactor, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.FailedValidationResponse(w, r, map[string]string{"two_factor_token": "invalid or expired two-factor token"})
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
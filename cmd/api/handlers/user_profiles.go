@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
@@ -14,7 +15,10 @@ import (
 // CreateUserProfile creates a new user profile
 func CreateUserProfile(appPtr *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		user := appPtr.ContextGetUser(r)
+		user, ok := readCurrentUser(appPtr, w, r)
+		if !ok {
+			return
+		}
 
 		_, err := appPtr.Models.UserProfile.GetByUserID(user.ID)
 		if err == nil {
@@ -25,6 +29,15 @@ func CreateUserProfile(appPtr *app.Application) http.HandlerFunc {
 			return
 		}
 
+		_, err = appPtr.Models.UserProfile.GetDeleted(user.ID, appPtr.Config.Trash.Retention)
+		if err == nil {
+			appPtr.FailedValidationResponse(w, r, map[string]string{"profile": "your deleted profile is in the trash; restore it instead"})
+			return
+		} else if !errors.Is(err, data.ErrRecordNotFound) {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
 		var input struct {
 			Firstname string   `json:"firstname"`
 			Lastname  string   `json:"lastname"`
@@ -84,7 +97,12 @@ func CreateUserProfile(appPtr *app.Application) http.HandlerFunc {
 		// Insert the profile
 		err = appPtr.Models.UserProfile.Insert(profile)
 		if err != nil {
-			appPtr.ServerErrorResponse(w, r, err)
+			switch {
+			case errors.Is(err, data.ErrDuplicateProfile):
+				appPtr.FailedValidationResponse(w, r, map[string]string{"profile": "your deleted profile is still being removed, please try again later"})
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
 			return
 		}
 
@@ -108,6 +126,58 @@ func CreateUserProfile(appPtr *app.Application) http.HandlerFunc {
 	}
 }
 
+// ListUserProfiles searches profiles by name, username, title, bio, faculty,
+// program and skills. Matching is fuzzy, so misspelt or partial terms still
+// find results, which are ordered by how closely they match. Pages are read by
+// number, or by keyset when the cursor parameter is given.
+func ListUserProfiles(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters data.Filters
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		query := appPtr.ReadString(qs, "q", "")
+		filter := data.ProfileFilter{
+			Faculty: appPtr.ReadString(qs, "faculty", ""),
+			Program: appPtr.ReadString(qs, "program", ""),
+			Degree:  appPtr.ReadString(qs, "degree", ""),
+			Year:    appPtr.ReadString(qs, "year", ""),
+			Uni:     appPtr.ReadString(qs, "uni", ""),
+		}
+
+		filters.Page = appPtr.ReadInt(qs, "page", 1, v)
+		filters.PageSize = appPtr.ReadInt(qs, "page_size", 20, v)
+		filters.Sort = "-relevance"
+		filters.SortSafelist = []string{"-relevance"}
+		filters.Keyset = qs.Has("cursor")
+		filters.Cursor = appPtr.ReadString(qs, "cursor", "")
+
+		v.Check(len(query) <= 100, "q", "must not be more than 100 bytes long")
+
+		if data.ValidateFilters(v, filters); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		profiles, metadata, err := appPtr.Models.UserProfile.Search(query, filter, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidCursor):
+				appPtr.FailedValidationResponse(w, r, map[string]string{"cursor": "must be a cursor returned by a previous page"})
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"metadata": metadata, "profiles": profiles}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
 // GetUserProfile retrieves a user profile
 func GetUserProfile(appPtr *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -246,6 +316,87 @@ func UpdateUserProfile(appPtr *app.Application) http.HandlerFunc {
 	}
 }
 
+// DeleteUserProfile moves the caller's profile to the trash. It can be
+// restored with RestoreUserProfile until it is purged.
+func DeleteUserProfile(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if id != user.ID {
+			appPtr.NotPermittedResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.UserProfile.Delete(user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		env := app.Envelope{
+			"message":  "profile moved to trash",
+			"purge_at": time.Now().Add(appPtr.Config.Trash.Retention),
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// RestoreUserProfile takes the caller's profile out of the trash if it was
+// deleted within the retention window.
+func RestoreUserProfile(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		if id != user.ID {
+			appPtr.NotPermittedResponse(w, r)
+			return
+		}
+
+		err = appPtr.Models.UserProfile.Restore(user.ID, appPtr.Config.Trash.Retention)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		profile, err := appPtr.Models.UserProfile.GetFullProfile(user.ID)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"profile": profile}, nil)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+	}
+}
+
 // Helper function to validate profile data
 func validateProfile(v *validator.Validator, profile *data.Profile) {
 	v.Check(profile.UserID != uuid.Nil, "user_id", "must be provided")
@@ -8,6 +8,7 @@ import (
     "github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
     "github.com/OpenConnectOUSL/backend-api-v1/internal/data"
     "github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+    "github.com/google/uuid"
 )
 
 // RegisterUser creates a new user account
@@ -164,6 +165,64 @@ func ActivateUser(appPtr *app.Application) http.HandlerFunc {
     }
 }
 
+// UnlockUser lifts a lockout after too many failed logins, using the token
+// sent to the account's email when it was locked
+func UnlockUser(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var input struct {
+            TokenPlaintext string `json:"token"`
+        }
+
+        err := appPtr.ReadJSON(w, r, &input)
+        if err != nil {
+            appPtr.BadRequestResponse(w, r, err)
+            return
+        }
+
+        v := validator.New()
+
+        if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+            appPtr.FailedValidationResponse(w, r, v.Errors)
+            return
+        }
+
+        user, err := appPtr.Models.Users.GetForToken(data.ScopeUnlock, input.TokenPlaintext)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                v.AddError("token", "invalid or expired unlock token")
+                appPtr.FailedValidationResponse(w, r, v.Errors)
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        err = appPtr.Models.LoginFailures.Clear(user.Email)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.Models.Tokens.DeleteAllForUser(data.ScopeUnlock, user.ID)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        logSecurityEvent(appPtr, "account_unlocked", map[string]string{
+            "email":      user.Email,
+            "email_hash": emailHash(user.Email),
+            "ip":         requestIP(r),
+        })
+
+        err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"message": "your account has been unlocked"}, nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
 // UpdateUserPassword updates a user's password using a reset token
 func UpdateUserPassword(appPtr *app.Application) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
@@ -223,6 +282,20 @@ func UpdateUserPassword(appPtr *app.Application) http.HandlerFunc {
             return
         }
 
+        // Whoever knew the old password is signed out everywhere
+        err = endSessions(appPtr, user.ID, uuid.Nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        // A new password also lifts any lockout from failed logins
+        err = appPtr.Models.LoginFailures.Clear(user.Email)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
         env := app.Envelope{"message": "your password was successfully reset"}
 
         err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
@@ -230,4 +303,107 @@ func UpdateUserPassword(appPtr *app.Application) http.HandlerFunc {
             appPtr.ServerErrorResponse(w, r, err)
         }
     }
-}
\ No newline at end of file
+}
+
+// DeleteUser moves a user account to the trash along with its profile and
+// ideas, and signs the user out everywhere. Users may delete their own
+// account; user admins may delete any.
+func DeleteUser(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, err := appPtr.ReadIDParam(r)
+        if err != nil {
+            appPtr.NotFoundResponse(w, r)
+            return
+        }
+
+        user := appPtr.ContextGetUser(r)
+
+        if id != user.ID {
+            isAdmin, err := appPtr.UserHasPermission(r, "users:admin")
+            if err != nil {
+                appPtr.ServerErrorResponse(w, r, err)
+                return
+            }
+            if !isAdmin {
+                appPtr.NotPermittedResponse(w, r)
+                return
+            }
+        }
+
+        // Deleting the account removes its tokens, so the sessions are read
+        // first to deny the signed access tokens still held for them
+        sessions, err := appPtr.Models.Tokens.GetSessionsForUser(id)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.Models.Users.Delete(id)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                appPtr.NotFoundResponse(w, r)
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        sessionIDs := make([]uuid.UUID, len(sessions))
+        for i, session := range sessions {
+            sessionIDs[i] = session.ID
+        }
+
+        err = appPtr.RevokeSignedTokens(sessionIDs...)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        env := app.Envelope{
+            "message":  "account moved to trash",
+            "purge_at": time.Now().Add(appPtr.Config.Trash.Retention),
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusOK, env, nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
+
+// RestoreUser brings back a deleted user account, with the profile and ideas
+// deleted along with it, if it is still within the retention window
+func RestoreUser(appPtr *app.Application) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, err := appPtr.ReadIDParam(r)
+        if err != nil {
+            appPtr.NotFoundResponse(w, r)
+            return
+        }
+
+        err = appPtr.Models.Users.Restore(id, appPtr.Config.Trash.Retention)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                appPtr.NotFoundResponse(w, r)
+            case errors.Is(err, data.ErrDuplicateEmail):
+                appPtr.FailedValidationResponse(w, r, map[string]string{"email": "is now used by another account"})
+            default:
+                appPtr.ServerErrorResponse(w, r, err)
+            }
+            return
+        }
+
+        user, err := appPtr.Models.Users.Get(id)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+            return
+        }
+
+        err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"user": user}, nil)
+        if err != nil {
+            appPtr.ServerErrorResponse(w, r, err)
+        }
+    }
+}
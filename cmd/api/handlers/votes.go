@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// VoteIdea records or changes the caller's vote on an idea
+func VoteIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		var input struct {
+			Value int `json:"value"`
+		}
+
+		err = appPtr.ReadJSON(w, r, &input)
+		if err != nil {
+			appPtr.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		if data.ValidateVote(v, input.Value); !v.Valid() {
+			appPtr.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		_, err = appPtr.Models.Ideas.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		err = appPtr.Models.Votes.Set(id, user.ID, input.Value)
+		if err != nil {
+			appPtr.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		writeVotedIdea(appPtr, w, r, id, input.Value)
+	}
+}
+
+// UnvoteIdea withdraws the caller's vote on an idea
+func UnvoteIdea(appPtr *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := appPtr.ReadIDParam(r)
+		if err != nil {
+			appPtr.NotFoundResponse(w, r)
+			return
+		}
+
+		user := appPtr.ContextGetUser(r)
+
+		err = appPtr.Models.Votes.Delete(id, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				appPtr.NotFoundResponse(w, r)
+			default:
+				appPtr.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		writeVotedIdea(appPtr, w, r, id, 0)
+	}
+}
+
+// writeVotedIdea responds with the idea's refreshed vote totals
+func writeVotedIdea(appPtr *app.Application, w http.ResponseWriter, r *http.Request, id uuid.UUID, userVote int) {
+	idea, err := appPtr.Models.Ideas.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			appPtr.NotFoundResponse(w, r)
+		default:
+			appPtr.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	idea.UserVote = userVote
+
+	err = appPtr.WriteJSON(w, http.StatusOK, app.Envelope{"idea": idea}, nil)
+	if err != nil {
+		appPtr.ServerErrorResponse(w, r, err)
+	}
+}
+
+// setUserVotes fills in the user's own vote on each idea in a listing
+func setUserVotes(appPtr *app.Application, user *data.User, ideas []*data.Idea) error {
+	ids := make([]uuid.UUID, len(ideas))
+	for i, idea := range ideas {
+		ids[i] = idea.ID
+	}
+
+	votes, err := appPtr.Models.Votes.GetForUser(user.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, idea := range ideas {
+		idea.UserVote = votes[idea.ID]
+	}
+
+	return nil
+}
@@ -44,6 +44,12 @@ func main() {
 	// Initialize Google OAuth
 	appPtr.InitGoogleOAuth()
 
+	// Initialize signed access tokens
+	err = appPtr.InitTokenSigning()
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	// Start server
 	err = server.Serve(appPtr)
 	if err != nil {
@@ -11,7 +11,9 @@ import (
 
 	"github.com/OpenConnectOUSL/backend-api-v1/cmd/api/app"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/data"
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/jwt"
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 )
 
@@ -111,6 +113,11 @@ func Authenticate(appPtr *app.Application) func(http.Handler) http.Handler {
 
 			token := headerParts[1]
 
+			if appPtr.Signer != nil && jwt.LooksSigned(token) {
+				authenticateSigned(appPtr, w, r, next, token)
+				return
+			}
+
 			v := validator.New()
 
 			if data.ValidateTokenPlaintext(v, token); !v.Valid() {
@@ -129,13 +136,55 @@ func Authenticate(appPtr *app.Application) func(http.Handler) http.Handler {
 				return
 			}
 
+			sessionID, err := appPtr.Models.Tokens.TouchSession(token)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					appPtr.InvalidAuthenticationTokenResponse(w, r)
+				default:
+					appPtr.ServerErrorResponse(w, r, err)
+				}
+				return
+			}
+
 			r = appPtr.ContextSetUser(r, user)
+			r = appPtr.ContextSetSessionID(r, sessionID)
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// authenticateSigned verifies a signed access token and takes the user and
+// their permissions from its claims, without a database lookup
+func authenticateSigned(appPtr *app.Application, w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	claims, err := appPtr.Signer.Verify(token, time.Now())
+	if err != nil {
+		appPtr.InvalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		appPtr.InvalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil || appPtr.Denylist.Contains(sessionID) {
+		appPtr.InvalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	user := &data.User{ID: userID, Activated: claims.Activated}
+
+	r = appPtr.ContextSetUser(r, user)
+	r = appPtr.ContextSetSessionID(r, sessionID)
+	r = appPtr.ContextSetPermissions(r, data.Permissions(claims.Permissions))
+
+	next.ServeHTTP(w, r)
+}
+
 // RequireAuthenticatedUser requires that the user is authenticated
 func RequireAuthenticatedUser(appPtr *app.Application) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
@@ -174,10 +223,15 @@ func RequirePermission(appPtr *app.Application, code string) func(http.HandlerFu
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			user := appPtr.ContextGetUser(r)
 
-			permissions, err := appPtr.Models.Permissions.GetAllForUser(user.ID)
-			if err != nil {
-				appPtr.ServerErrorResponse(w, r, err)
-				return
+			permissions, ok := appPtr.ContextGetPermissions(r)
+			if !ok {
+				var err error
+
+				permissions, err = appPtr.Models.Permissions.GetAllForUser(user.ID)
+				if err != nil {
+					appPtr.ServerErrorResponse(w, r, err)
+					return
+				}
 			}
 
 			if !permissions.Include(code) {
@@ -192,6 +246,52 @@ func RequirePermission(appPtr *app.Application, code string) func(http.HandlerFu
 	}
 }
 
+// RequireIdeaOwner loads the idea named by the :id URL parameter and only lets
+// the request through if the user owns it or holds the ideas:admin permission.
+// The loaded idea is stored in the request context for the next handler.
+func RequireIdeaOwner(appPtr *app.Application) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id, err := appPtr.ReadIDParam(r)
+			if err != nil {
+				appPtr.NotFoundResponse(w, r)
+				return
+			}
+
+			idea, err := appPtr.Models.Ideas.Get(id)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					appPtr.NotFoundResponse(w, r)
+				default:
+					appPtr.ServerErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			user := appPtr.ContextGetUser(r)
+
+			if idea.UserID != user.ID {
+				isAdmin, err := appPtr.UserHasPermission(r, "ideas:admin")
+				if err != nil {
+					appPtr.ServerErrorResponse(w, r, err)
+					return
+				}
+				if !isAdmin {
+					appPtr.NotPermittedResponse(w, r)
+					return
+				}
+			}
+
+			r = appPtr.ContextSetIdea(r, idea)
+
+			next.ServeHTTP(w, r)
+		}
+
+		return RequireActivatedUser(appPtr)(fn)
+	}
+}
+
 // EnableCORS enables CORS for trusted origins
 func EnableCORS(appPtr *app.Application) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
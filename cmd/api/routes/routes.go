@@ -23,27 +23,134 @@ func Setup(app *app.Application) http.Handler {
 	// Ideas routes
 	router.HandlerFunc(http.MethodGet, "/v1/ideas", middleware.RequirePermission(app, "ideas:read")(handlers.ListIdeas(app)))
 	router.HandlerFunc(http.MethodPost, "/v1/ideas", middleware.RequirePermission(app, "ideas:write")(handlers.CreateIdea(app)))
-	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:read")(handlers.ShowIdea(app)))
-	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:write")(handlers.UpdateIdea(app)))
-	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:write")(handlers.DeleteIdea(app)))
+	// Also serves the /v1/ideas/trending and /v1/ideas/activity feeds
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:read")(handlers.ShowIdeaOrFeed(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.UpdateIdea(app))))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.DeleteIdea(app))))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/restore", middleware.RequirePermission(app, "ideas:write")(handlers.RestoreIdea(app)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/me/ideas", middleware.RequireActivatedUser(app)(handlers.ListMyIdeas(app)))
+
+	// Idea status workflow routes
+	router.HandlerFunc(http.MethodPut, "/v1/ideas/:id/status", middleware.RequirePermission(app, "ideas:write")(handlers.ChangeIdeaStatus(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/status-history", middleware.RequirePermission(app, "ideas:read")(handlers.ListIdeaTransitions(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/moderation/ideas", middleware.RequirePermission(app, "ideas:moderate")(handlers.ListModerationQueue(app)))
+
+	// Idea revision routes
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/revisions", middleware.RequirePermission(app, "ideas:read")(handlers.ListIdeaRevisions(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/revisions/:version", middleware.RequirePermission(app, "ideas:read")(handlers.ShowIdeaRevision(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/revisions/:version/restore", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.RestoreIdeaRevision(app))))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/diff", middleware.RequirePermission(app, "ideas:read")(handlers.DiffIdeaRevisions(app)))
+
+	// Idea fork routes
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/fork", middleware.RequirePermission(app, "ideas:write")(handlers.ForkIdea(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/forks", middleware.RequirePermission(app, "ideas:read")(handlers.ListIdeaForks(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/lineage", middleware.RequirePermission(app, "ideas:read")(handlers.ShowIdeaLineage(app)))
+
+	// Idea team routes
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/team", middleware.RequirePermission(app, "ideas:read")(handlers.ListIdeaTeam(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/team", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.InviteTeamMember(app))))
+	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id/team/:user_id", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.UpdateTeamMember(app))))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id/team/:user_id", middleware.RequirePermission(app, "ideas:write")(handlers.RemoveTeamMember(app)))
+	router.HandlerFunc(http.MethodPut, "/v1/ideas/:id/invitation", middleware.RequirePermission(app, "ideas:write")(handlers.RespondToTeamInvitation(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/me/team-invitations", middleware.RequireActivatedUser(app)(handlers.ListTeamInvitations(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/teams", middleware.RequirePermission(app, "ideas:read")(handlers.ListUserTeams(app)))
+
+	// Idea join request routes
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/join-requests", middleware.RequirePermission(app, "ideas:write")(handlers.CreateJoinRequest(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/join-requests", middleware.RequirePermission(app, "ideas:read")(middleware.RequireIdeaOwner(app)(handlers.ListJoinRequests(app))))
+	router.HandlerFunc(http.MethodPut, "/v1/ideas/:id/join-requests/:request_id/accept", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.AcceptJoinRequest(app))))
+	router.HandlerFunc(http.MethodPut, "/v1/ideas/:id/join-requests/:request_id/reject", middleware.RequirePermission(app, "ideas:write")(middleware.RequireIdeaOwner(app)(handlers.RejectJoinRequest(app))))
+
+	// Idea vote routes
+	router.HandlerFunc(http.MethodPut, "/v1/ideas/:id/vote", middleware.RequirePermission(app, "ideas:write")(handlers.VoteIdea(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id/vote", middleware.RequirePermission(app, "ideas:write")(handlers.UnvoteIdea(app)))
+
+	// Idea comment routes
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/comments", middleware.RequirePermission(app, "ideas:read")(handlers.ListComments(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/comments", middleware.RequirePermission(app, "ideas:write")(handlers.CreateComment(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id/comments/:comment_id", middleware.RequirePermission(app, "ideas:write")(handlers.UpdateComment(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id/comments/:comment_id", middleware.RequirePermission(app, "ideas:write")(handlers.DeleteComment(app)))
+
+	// Idea milestone and task routes
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/milestones", middleware.RequirePermission(app, "ideas:read")(handlers.ListMilestones(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/milestones", middleware.RequirePermission(app, "ideas:write")(handlers.CreateMilestone(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id/milestones/:milestone_id", middleware.RequirePermission(app, "ideas:write")(handlers.UpdateMilestone(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id/milestones/:milestone_id", middleware.RequirePermission(app, "ideas:write")(handlers.DeleteMilestone(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/ideas/:id/tasks", middleware.RequirePermission(app, "ideas:read")(handlers.ListTasks(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/ideas/:id/tasks", middleware.RequirePermission(app, "ideas:write")(handlers.CreateTask(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/ideas/:id/tasks/:task_id", middleware.RequirePermission(app, "ideas:write")(handlers.UpdateTask(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/ideas/:id/tasks/:task_id", middleware.RequirePermission(app, "ideas:write")(handlers.DeleteTask(app)))
+
+	// Category routes
+	router.HandlerFunc(http.MethodGet, "/v1/categories", middleware.RequirePermission(app, "ideas:read")(handlers.ListCategories(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/categories", middleware.RequirePermission(app, "categories:manage")(handlers.CreateCategory(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:slug", middleware.RequirePermission(app, "ideas:read")(handlers.ShowCategory(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/categories/:slug", middleware.RequirePermission(app, "categories:manage")(handlers.UpdateCategory(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/categories/:slug", middleware.RequirePermission(app, "categories:manage")(handlers.DeleteCategory(app)))
+
+	// Trash routes
+	router.HandlerFunc(http.MethodGet, "/v1/me/trash", middleware.RequireActivatedUser(app)(handlers.ListTrash(app)))
+
+	// Recommendation routes
+	router.HandlerFunc(http.MethodGet, "/v1/me/recommended-ideas", middleware.RequireActivatedUser(app)(handlers.ListRecommendedIdeas(app)))
+
+	// Bookmark routes
+	router.HandlerFunc(http.MethodGet, "/v1/me/bookmarks", middleware.RequireActivatedUser(app)(handlers.ListBookmarks(app)))
+	router.HandlerFunc(http.MethodPut, "/v1/me/bookmarks/:id", middleware.RequireActivatedUser(app)(handlers.BookmarkIdea(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/bookmarks/:id", middleware.RequireActivatedUser(app)(handlers.UnbookmarkIdea(app)))
+
+	// Collection routes
+	router.HandlerFunc(http.MethodGet, "/v1/collections", middleware.RequireActivatedUser(app)(handlers.ListCollections(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/collections", middleware.RequireActivatedUser(app)(handlers.CreateCollection(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/collections/:id", middleware.RequireActivatedUser(app)(handlers.ShowCollection(app)))
+	router.HandlerFunc(http.MethodPatch, "/v1/collections/:id", middleware.RequireActivatedUser(app)(handlers.UpdateCollection(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id", middleware.RequireActivatedUser(app)(handlers.DeleteCollection(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/collections/:id/ideas", middleware.RequireActivatedUser(app)(handlers.ListCollectionIdeas(app)))
+	router.HandlerFunc(http.MethodPut, "/v1/collections/:id/ideas/:idea_id", middleware.RequireActivatedUser(app)(handlers.AddCollectionIdea(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id/ideas/:idea_id", middleware.RequireActivatedUser(app)(handlers.RemoveCollectionIdea(app)))
+	router.HandlerFunc(http.MethodGet, "/v1/shared-collections/:token", handlers.ShowSharedCollection(app))
 
 	// User routes
 	router.HandlerFunc(http.MethodPost, "/v1/users", handlers.RegisterUser(app))
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", handlers.ActivateUser(app))
 	router.HandlerFunc(http.MethodPut, "/v1/users/password-reset", handlers.UpdateUserPassword(app))
+	router.HandlerFunc(http.MethodPut, "/v1/users/unlocked", handlers.UnlockUser(app))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id", middleware.RequireActivatedUser(app)(handlers.DeleteUser(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/users/:id/restore", middleware.RequirePermission(app, "users:admin")(handlers.RestoreUser(app)))
 
 	// Authentication token routes
 	router.HandlerFunc(http.MethodPost, "/v1/auth/tokens/authentication", handlers.CreateAuthenticationToken(app))
+	router.HandlerFunc(http.MethodDelete, "/v1/auth/tokens/authentication", middleware.RequireAuthenticatedUser(app)(handlers.DeleteAuthenticationToken(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/auth/tokens/refresh", handlers.RefreshAuthenticationToken(app))
+	router.HandlerFunc(http.MethodPost, "/v1/auth/tokens/two-factor", handlers.CreateTwoFactorAuthenticationToken(app))
+	router.HandlerFunc(http.MethodPost, "/v1/auth/two-factor/enrolment", handlers.EnrolTwoFactorAtLogin(app))
 	router.HandlerFunc(http.MethodPost, "/v1/auth/tokens/password-reset-request", handlers.CreatePasswordResetToken(app))
 
+	// Session routes
+	router.HandlerFunc(http.MethodGet, "/v1/me/sessions", middleware.RequireAuthenticatedUser(app)(handlers.ListSessions(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/sessions", middleware.RequireAuthenticatedUser(app)(handlers.RevokeAllSessions(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/sessions/:id", middleware.RequireAuthenticatedUser(app)(handlers.RevokeSession(app)))
+
+	// Two-factor authentication routes
+	router.HandlerFunc(http.MethodGet, "/v1/me/two-factor", middleware.RequireAuthenticatedUser(app)(handlers.ShowTwoFactor(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/me/two-factor", middleware.RequireAuthenticatedUser(app)(handlers.EnrolTwoFactor(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/two-factor", middleware.RequireAuthenticatedUser(app)(handlers.DisableTwoFactor(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/me/two-factor/confirm", middleware.RequireAuthenticatedUser(app)(handlers.ConfirmTwoFactor(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/me/two-factor/recovery-codes", middleware.RequireAuthenticatedUser(app)(handlers.RegenerateRecoveryCodes(app)))
+
 	// OAuth routes
 	router.HandlerFunc(http.MethodGet, "/v1/auth/google/login", handlers.GoogleLogin(app))
 	router.HandlerFunc(http.MethodGet, "/v1/auth/google/callback", handlers.GoogleCallback(app))
+	router.HandlerFunc(http.MethodPost, "/v1/auth/tokens/oauth", handlers.CreateOAuthAuthenticationToken(app))
 
 	// User profile routes
+	router.HandlerFunc(http.MethodGet, "/v1/user-profiles", middleware.RequireAuthenticatedUser(app)(handlers.ListUserProfiles(app)))
 	router.HandlerFunc(http.MethodPost, "/v1/user-profiles", middleware.RequireActivatedUser(app)(handlers.CreateUserProfile(app)))
 	router.HandlerFunc(http.MethodGet, "/v1/user-profiles/:id", middleware.RequireAuthenticatedUser(app)(handlers.GetUserProfile(app)))
 	router.HandlerFunc(http.MethodPatch, "/v1/user-profiles/:id", middleware.RequireActivatedUser(app)(handlers.UpdateUserProfile(app)))
+	router.HandlerFunc(http.MethodDelete, "/v1/user-profiles/:id", middleware.RequireActivatedUser(app)(handlers.DeleteUserProfile(app)))
+	router.HandlerFunc(http.MethodPost, "/v1/user-profiles/:id/restore", middleware.RequireActivatedUser(app)(handlers.RestoreUserProfile(app)))
 
 	// File upload routes
 	router.HandlerFunc(http.MethodPost, "/v1/files/upload", middleware.RequireAuthenticatedUser(app)(handlers.ServePDFHandler(app)))
@@ -24,6 +24,12 @@ func Serve(appPtr *app.Application) error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Background jobs run until the server starts shutting down
+	ctx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	startBackgroundJobs(ctx, appPtr)
+
 	shutdownError := make(chan error)
 
 	// Background goroutine for graceful shutdown
@@ -45,6 +51,8 @@ func Serve(appPtr *app.Application) error {
 			shutdownError <- err
 		}
 
+		stopJobs()
+
 		appPtr.Logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
@@ -74,3 +82,23 @@ func Serve(appPtr *app.Application) error {
 
 	return nil
 }
+
+// startBackgroundJobs starts the periodic jobs the API depends on. They stop
+// when ctx is cancelled.
+func startBackgroundJobs(ctx context.Context, appPtr *app.Application) {
+	if appPtr.Config.Feeds.RefreshInterval > 0 {
+		appPtr.RunPeriodically(ctx, "refresh idea activity stats", appPtr.Config.Feeds.RefreshInterval, appPtr.Models.Ideas.RefreshActivityStats)
+	}
+	if appPtr.Config.Drafts.PublishInterval > 0 {
+		appPtr.RunPeriodically(ctx, "publish scheduled ideas", appPtr.Config.Drafts.PublishInterval, appPtr.PublishScheduledIdeas)
+	}
+	if appPtr.Config.Trash.PurgeInterval > 0 {
+		appPtr.RunPeriodically(ctx, "purge trash", appPtr.Config.Trash.PurgeInterval, appPtr.PurgeTrash)
+	}
+	if appPtr.Signer != nil && appPtr.Config.Auth.DenylistSyncInterval > 0 {
+		appPtr.RunPeriodically(ctx, "sync token denylist", appPtr.Config.Auth.DenylistSyncInterval, appPtr.SyncDenylist)
+	}
+	if appPtr.Config.Auth.LoginFailureWindow > 0 {
+		appPtr.RunPeriodically(ctx, "purge login failures", appPtr.Config.Auth.LoginFailureWindow, appPtr.PurgeLoginFailures)
+	}
+}
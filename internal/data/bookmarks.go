@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BookmarkModel struct {
+	DB *sql.DB
+}
+
+// Insert bookmarks the idea for the user. Bookmarking an idea twice is not an error.
+func (m BookmarkModel) Insert(userID, ideaID uuid.UUID) error {
+	query := `INSERT INTO bookmarks (user_id, idea_id)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id, idea_id) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, ideaID)
+	return err
+}
+
+func (m BookmarkModel) Delete(userID, ideaID uuid.UUID) error {
+	query := `DELETE FROM bookmarks
+	          WHERE user_id = $1 AND idea_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, ideaID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForUser lists the ideas a user has bookmarked. Ideas that are no
+// longer public are left out unless the user owns them.
+func (m BookmarkModel) GetAllForUser(userID uuid.UUID, filters Filters) ([]*Idea, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), i.id, i.created_at, i.updated_at, i.title, i.description,
+                                i.user_id, i.idea_source_id, COALESCE(i.category, ''), i.tags, i.status, i.learning_outcome,
+                                i.recommended_level, i.github_link, i.website_link, COALESCE(i.pdf, ''),
+                                i.upvotes, i.downvotes, i.votes, i.version, b.created_at AS bookmarked_at
+                          FROM bookmarks b
+                          INNER JOIN ideas i ON i.id = b.idea_id AND i.deleted_at IS NULL
+                          WHERE b.user_id = $1
+                          AND (i.status = ANY($2) OR i.user_id = $1)
+                          ORDER BY %s %s, i.id ASC
+                          LIMIT $3 OFFSET $4`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(PublicIdeaStatuses), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ideas := []*Idea{}
+
+	for rows.Next() {
+		var idea Idea
+		var bookmarkedAt time.Time
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&bookmarkedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		idea.IsBookmarked = true
+		ideas = append(ideas, &idea)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return ideas, metadata, nil
+}
+
+// GetForUser reports which of the given ideas the user has bookmarked.
+func (m BookmarkModel) GetForUser(userID uuid.UUID, ideaIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	bookmarked := make(map[uuid.UUID]bool)
+	if len(ideaIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	ids := make([]string, len(ideaIDs))
+	for i, id := range ideaIDs {
+		ids[i] = id.String()
+	}
+
+	query := `SELECT idea_id
+	          FROM bookmarks
+	          WHERE user_id = $1 AND idea_id = ANY($2::uuid[])`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ideaID uuid.UUID
+
+		if err := rows.Scan(&ideaID); err != nil {
+			return nil, err
+		}
+
+		bookmarked[ideaID] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bookmarked, nil
+}
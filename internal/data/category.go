@@ -1,3 +1,291 @@
 package data
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateSlug = errors.New("duplicate slug")
+	ErrCategoryInUse = errors.New("category in use")
+)
+
+var SlugRX = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Category struct {
+	ID         uuid.UUID   `json:"id"`
+	Slug       string      `json:"slug"`
+	Name       string      `json:"name"`
+	ParentID   *uuid.UUID  `json:"parent_id,omitempty"`
+	ParentSlug string      `json:"parent,omitempty"`
+	Children   []*Category `json:"children,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Version    int         `json:"version"`
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(category.Slug != "", "slug", "must be provided")
+	v.Check(len(category.Slug) <= 50, "slug", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(category.Slug, SlugRX), "slug", "must contain only lowercase letters, digits and single hyphens")
+
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(category.ParentSlug != category.Slug, "parent", "must not be the category itself")
+}
+
+// CategoryTree arranges a flat list of categories under their parents and
+// returns the top-level ones.
+func CategoryTree(categories []*Category) []*Category {
+	byID := make(map[uuid.UUID]*Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	roots := []*Category{}
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+
+		parent, ok := byID[*category.ParentID]
+		if !ok {
+			roots = append(roots, category)
+			continue
+		}
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func (m CategoryModel) Insert(category *Category) error {
+	query := `INSERT INTO categories (slug, name, parent_id)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, updated_at, version`
+
+	args := []any{category.Slug, category.Name, category.ParentID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt, &category.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_slug_key"`:
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CategoryModel) Get(slug string) (*Category, error) {
+	query := `SELECT c.id, c.slug, c.name, c.parent_id, COALESCE(p.slug, ''), c.created_at, c.updated_at, c.version
+	          FROM categories c
+	          LEFT JOIN categories p ON p.id = c.parent_id
+	          WHERE c.slug = $1`
+
+	var category Category
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, slug).Scan(
+		&category.ID,
+		&category.Slug,
+		&category.Name,
+		&category.ParentID,
+		&category.ParentSlug,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+		&category.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+// Update saves the category. Changing the slug is carried through to every
+// idea in the category by the foreign key.
+func (m CategoryModel) Update(category *Category) error {
+	query := `UPDATE categories
+	          SET slug = $1, name = $2, parent_id = $3, updated_at = NOW(), version = version + 1
+	          WHERE id = $4 AND version = $5
+	          RETURNING updated_at, version`
+
+	args := []any{category.Slug, category.Name, category.ParentID, category.ID, category.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&category.UpdatedAt, &category.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_slug_key"`:
+			return ErrDuplicateSlug
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a category. Ideas in the category are moved to reassignTo
+// when it is set; otherwise ErrCategoryInUse is returned while any idea or
+// subcategory still refers to it.
+func (m CategoryModel) Delete(slug, reassignTo string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if reassignTo != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE ideas SET category = $1 WHERE category = $2`, reassignTo, slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE slug = $1`, slug)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), `pq: update or delete on table "categories" violates foreign key constraint`):
+			return ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// GetAll returns every category ordered by name.
+func (m CategoryModel) GetAll() ([]*Category, error) {
+	query := `SELECT c.id, c.slug, c.name, c.parent_id, COALESCE(p.slug, ''), c.created_at, c.updated_at, c.version
+	          FROM categories c
+	          LEFT JOIN categories p ON p.id = c.parent_id
+	          ORDER BY c.name ASC, c.slug ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err := rows.Scan(
+			&category.ID,
+			&category.Slug,
+			&category.Name,
+			&category.ParentID,
+			&category.ParentSlug,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// Exists reports whether a category with the slug exists. It is used to
+// validate an idea's category without loading the whole taxonomy.
+func (m CategoryModel) Exists(slug string) (bool, error) {
+	if slug == "" {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+
+	err := m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM categories WHERE slug = $1)`, slug).Scan(&exists)
+
+	return exists, err
+}
+
+// GetSubtreeSlugs returns the slug of the category and of all its descendants.
+func (m CategoryModel) GetSubtreeSlugs(slug string) ([]string, error) {
+	query := `WITH RECURSIVE subtree AS (
+	              SELECT id, slug FROM categories WHERE slug = $1
+	              UNION
+	              SELECT c.id, c.slug FROM categories c INNER JOIN subtree s ON c.parent_id = s.id
+	          )
+	          SELECT slug FROM subtree`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, s)
+	}
+
+	return slugs, rows.Err()
+}
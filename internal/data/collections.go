@@ -0,0 +1,350 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	CollectionPrivate = "private"
+	CollectionLink    = "link"
+)
+
+type Collection struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Visibility  string    `json:"visibility"`
+	ShareToken  string    `json:"share_token,omitempty"`
+	IdeaCount   int       `json:"idea_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int       `json:"version"`
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Name != "", "name", "must be provided")
+	v.Check(len(collection.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(collection.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(validator.PermittedValue(collection.Visibility, CollectionPrivate, CollectionLink), "visibility", "must be private or link")
+}
+
+// SetVisibility changes who can see the collection. Sharing by link issues a
+// new share token if the collection does not have one; making it private
+// revokes the token so old links stop working.
+func (c *Collection) SetVisibility(visibility string) error {
+	c.Visibility = visibility
+
+	switch {
+	case visibility == CollectionLink && c.ShareToken == "":
+		randomBytes := make([]byte, 16)
+
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return err
+		}
+
+		c.ShareToken = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	case visibility != CollectionLink:
+		c.ShareToken = ""
+	}
+
+	return nil
+}
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+func (m CollectionModel) Insert(collection *Collection) error {
+	query := `INSERT INTO collections (user_id, name, description, visibility, share_token)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	RETURNING id, created_at, updated_at, version`
+
+	args := []any{collection.UserID, collection.Name, collection.Description, collection.Visibility, collection.ShareToken}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.ID, &collection.CreatedAt, &collection.UpdatedAt, &collection.Version)
+}
+
+func (m CollectionModel) Get(id uuid.UUID) (*Collection, error) {
+	return m.getBy("c.id = $1", id)
+}
+
+// GetByShareToken returns the collection shared under the given token.
+func (m CollectionModel) GetByShareToken(token string) (*Collection, error) {
+	return m.getBy("c.share_token = $1 AND c.visibility = 'link'", token)
+}
+
+func (m CollectionModel) getBy(condition string, arg any) (*Collection, error) {
+	query := fmt.Sprintf(`SELECT c.id, c.user_id, c.name, c.description, c.visibility, COALESCE(c.share_token, ''),
+                                (SELECT count(*) FROM collection_ideas ci
+                                 INNER JOIN ideas i ON i.id = ci.idea_id AND i.deleted_at IS NULL
+                                 WHERE ci.collection_id = c.id),
+                                c.created_at, c.updated_at, c.version
+                          FROM collections c
+                          WHERE %s`, condition)
+
+	var collection Collection
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, arg).Scan(
+		&collection.ID,
+		&collection.UserID,
+		&collection.Name,
+		&collection.Description,
+		&collection.Visibility,
+		&collection.ShareToken,
+		&collection.IdeaCount,
+		&collection.CreatedAt,
+		&collection.UpdatedAt,
+		&collection.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
+
+func (m CollectionModel) Update(collection *Collection) error {
+	query := `UPDATE collections
+	          SET name = $1, description = $2, visibility = $3, share_token = NULLIF($4, ''),
+	              updated_at = NOW(), version = version + 1
+	          WHERE id = $5 AND version = $6
+	          RETURNING updated_at, version`
+
+	args := []any{
+		collection.Name,
+		collection.Description,
+		collection.Visibility,
+		collection.ShareToken,
+		collection.ID,
+		collection.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.UpdatedAt, &collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CollectionModel) Delete(id uuid.UUID) error {
+	query := `DELETE FROM collections
+	          WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForUser lists the collections a user has created.
+func (m CollectionModel) GetAllForUser(userID uuid.UUID, filters Filters) ([]*Collection, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), c.id, c.user_id, c.name, c.description, c.visibility,
+                                COALESCE(c.share_token, ''),
+                                (SELECT count(*) FROM collection_ideas ci
+                                 INNER JOIN ideas i ON i.id = ci.idea_id AND i.deleted_at IS NULL
+                                 WHERE ci.collection_id = c.id),
+                                c.created_at, c.updated_at, c.version
+                          FROM collections c
+                          WHERE c.user_id = $1
+                          ORDER BY %s %s, c.id ASC
+                          LIMIT $2 OFFSET $3`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	collections := []*Collection{}
+
+	for rows.Next() {
+		var collection Collection
+
+		err := rows.Scan(
+			&totalRecords,
+			&collection.ID,
+			&collection.UserID,
+			&collection.Name,
+			&collection.Description,
+			&collection.Visibility,
+			&collection.ShareToken,
+			&collection.IdeaCount,
+			&collection.CreatedAt,
+			&collection.UpdatedAt,
+			&collection.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		collections = append(collections, &collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return collections, metadata, nil
+}
+
+// AddIdea puts an idea in a collection. Adding the same idea twice is not an error.
+func (m CollectionModel) AddIdea(collectionID, ideaID uuid.UUID) error {
+	query := `INSERT INTO collection_ideas (collection_id, idea_id)
+	VALUES ($1, $2)
+	ON CONFLICT (collection_id, idea_id) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, collectionID, ideaID)
+	return err
+}
+
+func (m CollectionModel) RemoveIdea(collectionID, ideaID uuid.UUID) error {
+	query := `DELETE FROM collection_ideas
+	          WHERE collection_id = $1 AND idea_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, collectionID, ideaID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetIdeas lists the ideas in a collection that the viewer may see: public
+// ideas and, when viewerID is set, the viewer's own ideas.
+func (m CollectionModel) GetIdeas(collectionID, viewerID uuid.UUID, filters Filters) ([]*Idea, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), i.id, i.created_at, i.updated_at, i.title, i.description,
+                                i.user_id, i.idea_source_id, COALESCE(i.category, ''), i.tags, i.status, i.learning_outcome,
+                                i.recommended_level, i.github_link, i.website_link, COALESCE(i.pdf, ''),
+                                i.upvotes, i.downvotes, i.votes, i.version, ci.added_at
+                          FROM collection_ideas ci
+                          INNER JOIN ideas i ON i.id = ci.idea_id AND i.deleted_at IS NULL
+                          WHERE ci.collection_id = $1
+                          AND (i.status = ANY($2) OR i.user_id = $3)
+                          ORDER BY %s %s, i.id ASC
+                          LIMIT $4 OFFSET $5`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{collectionID, pq.Array(PublicIdeaStatuses), viewerID, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ideas := []*Idea{}
+
+	for rows.Next() {
+		var idea Idea
+		var addedAt time.Time
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&addedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		ideas = append(ideas, &idea)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return ideas, metadata, nil
+}
@@ -0,0 +1,254 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Comment struct {
+	ID          uuid.UUID  `json:"id"`                  // Unique identifier for the comment
+	IdeaID      uuid.UUID  `json:"idea_id"`             // ID of the idea the comment is related to
+	CommentedBy uuid.UUID  `json:"commented_by"`        // User ID of the person who made the comment
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"` // ID of the comment this one replies to, if any
+	Content     string     `json:"content"`             // Content of the comment
+	CreatedAt   time.Time  `json:"created_at"`          // Timestamp of when the comment was created
+	UpdatedAt   time.Time  `json:"updated_at"`          // Timestamp of the last edit
+	Version     int        `json:"version"`
+	Replies     []*Comment `json:"replies,omitempty"`
+}
+
+func ValidateComment(v *validator.Validator, comment *Comment) {
+	v.Check(comment.Content != "", "content", "must be provided")
+	v.Check(len(comment.Content) <= 2000, "content", "must not be more than 2000 bytes long")
+}
+
+type CommentModel struct {
+	DB *sql.DB
+}
+
+func (m CommentModel) Insert(comment *Comment) error {
+	query := `INSERT INTO comments (idea_id, commented_by, parent_id, content)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at, version`
+
+	args := []any{comment.IdeaID, comment.CommentedBy, comment.ParentID, comment.Content}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt, &comment.Version)
+}
+
+func (m CommentModel) Get(id uuid.UUID) (*Comment, error) {
+	query := `SELECT id, idea_id, commented_by, parent_id, content, created_at, updated_at, version
+             FROM comments
+             WHERE id = $1`
+
+	var comment Comment
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&comment.ID,
+		&comment.IdeaID,
+		&comment.CommentedBy,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &comment, nil
+}
+
+func (m CommentModel) Update(comment *Comment) error {
+	query := `UPDATE comments
+              SET content = $1, updated_at = NOW(), version = version + 1
+              WHERE id = $2 AND version = $3
+              RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, comment.Content, comment.ID, comment.Version).Scan(&comment.UpdatedAt, &comment.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a comment together with all of its replies.
+func (m CommentModel) Delete(id uuid.UUID) error {
+	query := `DELETE FROM comments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForIdea returns a page of top-level comments for an idea, each with
+// its full reply thread nested under Replies.
+func (m CommentModel) GetAllForIdea(ideaID uuid.UUID, filters Filters) ([]*Comment, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, idea_id, commented_by, parent_id, content,
+                                created_at, updated_at, version
+                          FROM comments
+                          WHERE idea_id = $1 AND parent_id IS NULL
+                          ORDER BY %s %s, id ASC
+                          LIMIT $2 OFFSET $3`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	comments := []*Comment{}
+	rootIDs := []uuid.UUID{}
+
+	for rows.Next() {
+		var comment Comment
+
+		err := rows.Scan(
+			&totalRecords,
+			&comment.ID,
+			&comment.IdeaID,
+			&comment.CommentedBy,
+			&comment.ParentID,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		comments = append(comments, &comment)
+		rootIDs = append(rootIDs, comment.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if len(rootIDs) > 0 {
+		err = m.attachReplies(ctx, comments, rootIDs)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return comments, metadata, nil
+}
+
+func (m CommentModel) attachReplies(ctx context.Context, roots []*Comment, rootIDs []uuid.UUID) error {
+	query := `
+        WITH RECURSIVE thread AS (
+            SELECT id, idea_id, commented_by, parent_id, content, created_at, updated_at, version
+            FROM comments
+            WHERE parent_id = ANY($1)
+            UNION ALL
+            SELECT c.id, c.idea_id, c.commented_by, c.parent_id, c.content, c.created_at, c.updated_at, c.version
+            FROM comments c
+            INNER JOIN thread t ON c.parent_id = t.id
+        )
+        SELECT id, idea_id, commented_by, parent_id, content, created_at, updated_at, version
+        FROM thread
+        ORDER BY created_at ASC, id ASC`
+
+	ids := make([]string, len(rootIDs))
+	for i, id := range rootIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*Comment, len(roots))
+	for _, root := range roots {
+		byID[root.ID] = root
+	}
+
+	var replies []*Comment
+
+	for rows.Next() {
+		var comment Comment
+
+		err := rows.Scan(
+			&comment.ID,
+			&comment.IdeaID,
+			&comment.CommentedBy,
+			&comment.ParentID,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		byID[comment.ID] = &comment
+		replies = append(replies, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Replies are ordered by creation time, so appending keeps each thread chronological.
+	for _, reply := range replies {
+		if parent, ok := byID[*reply.ParentID]; ok {
+			parent.Replies = append(parent.Replies, reply)
+		}
+	}
+
+	return nil
+}
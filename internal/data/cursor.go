@@ -0,0 +1,152 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks a row in a keyset-paginated list by the values of its sort
+// keys, the last of which is always the row's id. Before asks for the page
+// preceding the row instead of the one following it. Sort records the sort
+// order the cursor was issued for, since the keys are meaningless under
+// another.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Keys   []string `json:"k"`
+	Before bool     `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(js, &c); err != nil || len(c.Keys) == 0 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// keyset returns the condition and ORDER BY clause selecting the page after
+// (or before) the cursor, ordered by the given expressions in the direction
+// of the sort. The expressions must identify a row uniquely, so the last one
+// is usually the id. Placeholders are numbered from argPos.
+func (f Filters) keyset(exprs []string, argPos int) (string, string, []any, error) {
+	descending := f.sortDirection() == "DESC"
+
+	condition := "TRUE"
+	args := []any{}
+
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil || c.Sort != f.Sort || len(c.Keys) != len(exprs) {
+			return "", "", nil, ErrInvalidCursor
+		}
+
+		// A page before the cursor is read backwards and put back in order
+		// by keysetPage
+		if c.Before {
+			descending = !descending
+		}
+
+		placeholders := make([]string, len(c.Keys))
+		for i, key := range c.Keys {
+			args = append(args, key)
+			placeholders[i] = fmt.Sprintf("$%d", argPos+i)
+		}
+
+		operator := ">"
+		if descending {
+			operator = "<"
+		}
+
+		condition = fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), operator, strings.Join(placeholders, ", "))
+	}
+
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+
+	order := make([]string, len(exprs))
+	for i, expr := range exprs {
+		order[i] = expr + " " + direction
+	}
+
+	return condition, strings.Join(order, ", "), args, nil
+}
+
+// cursorQueryError turns the error Postgres returns when a cursor key cannot
+// be compared with its column, such as a malformed timestamp or UUID, into
+// ErrInvalidCursor. Cursors come from the client, so a tampered one is a bad
+// request rather than a server error.
+func cursorQueryError(err error, f Filters) error {
+	var pqErr *pq.Error
+	if f.Cursor != "" && errors.As(err, &pqErr) && pqErr.Code.Class() == "22" {
+		return ErrInvalidCursor
+	}
+	return err
+}
+
+// keysetPage takes the rows of a keyset query, which fetches one row more
+// than the page size to learn whether another page follows, and returns the
+// page in sort order with cursors to the pages either side of it.
+func keysetPage[T any](items []T, f Filters, keys func(T) []string) ([]T, Metadata) {
+	var c cursor
+	if f.Cursor != "" {
+		c, _ = decodeCursor(f.Cursor)
+	}
+
+	more := len(items) > f.PageSize
+	if more {
+		items = items[:f.PageSize]
+	}
+
+	if c.Before {
+		slices.Reverse(items)
+	}
+
+	metadata := Metadata{PageSize: f.PageSize}
+
+	if len(items) == 0 {
+		return items, metadata
+	}
+
+	next := cursor{Sort: f.Sort, Keys: keys(items[len(items)-1])}
+	prev := cursor{Sort: f.Sort, Keys: keys(items[0]), Before: true}
+
+	// Reading forwards, a cursor means rows were skipped before this page;
+	// reading backwards, the cursor row itself follows it
+	if c.Before {
+		metadata.NextCursor = next.encode()
+		if more {
+			metadata.PrevCursor = prev.encode()
+		}
+	} else {
+		if more {
+			metadata.NextCursor = next.encode()
+		}
+		if f.Cursor != "" {
+			metadata.PrevCursor = prev.encode()
+		}
+	}
+
+	return items, metadata
+}
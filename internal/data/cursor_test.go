@@ -0,0 +1,246 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := cursor{Sort: "-created_at", Keys: []string{"2024-01-02T03:04:05Z", "4b3f8e2a-33a4-4d4b-9a8e-2b7c6c0f1d11"}, Before: true}
+
+	got, err := decodeCursor(c.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, c) {
+		t.Fatalf("got %+v, want %+v", got, c)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm90IGpzb24"},
+		{"no keys", cursor{Sort: "id"}.encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("got error %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	exprs := []string{"created_at", "id"}
+
+	tests := []struct {
+		name      string
+		filters   Filters
+		condition string
+		order     string
+		args      []any
+	}{
+		{
+			name:      "first page ascending",
+			filters:   Filters{Sort: "created_at"},
+			condition: "TRUE",
+			order:     "created_at ASC, id ASC",
+			args:      []any{},
+		},
+		{
+			name:      "first page descending",
+			filters:   Filters{Sort: "-created_at"},
+			condition: "TRUE",
+			order:     "created_at DESC, id DESC",
+			args:      []any{},
+		},
+		{
+			name:      "after cursor ascending",
+			filters:   Filters{Sort: "created_at", Cursor: cursor{Sort: "created_at", Keys: []string{"t1", "id1"}}.encode()},
+			condition: "(created_at, id) > ($3, $4)",
+			order:     "created_at ASC, id ASC",
+			args:      []any{"t1", "id1"},
+		},
+		{
+			name:      "after cursor descending",
+			filters:   Filters{Sort: "-created_at", Cursor: cursor{Sort: "-created_at", Keys: []string{"t1", "id1"}}.encode()},
+			condition: "(created_at, id) < ($3, $4)",
+			order:     "created_at DESC, id DESC",
+			args:      []any{"t1", "id1"},
+		},
+		{
+			name:      "before cursor descending reads backwards",
+			filters:   Filters{Sort: "-created_at", Cursor: cursor{Sort: "-created_at", Keys: []string{"t1", "id1"}, Before: true}.encode()},
+			condition: "(created_at, id) > ($3, $4)",
+			order:     "created_at ASC, id ASC",
+			args:      []any{"t1", "id1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, order, args, err := tt.filters.keyset(exprs, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if condition != tt.condition {
+				t.Errorf("got condition %q, want %q", condition, tt.condition)
+			}
+			if order != tt.order {
+				t.Errorf("got order %q, want %q", order, tt.order)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("got args %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestKeysetRejectsMismatchedCursor(t *testing.T) {
+	exprs := []string{"created_at", "id"}
+
+	tests := []struct {
+		name    string
+		filters Filters
+	}{
+		{"invalid cursor", Filters{Sort: "created_at", Cursor: "!!!"}},
+		{"other sort", Filters{Sort: "-created_at", Cursor: cursor{Sort: "created_at", Keys: []string{"t1", "id1"}}.encode()}},
+		{"wrong number of keys", Filters{Sort: "created_at", Cursor: cursor{Sort: "created_at", Keys: []string{"id1"}}.encode()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := tt.filters.keyset(exprs, 1); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("got error %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	keys := func(s string) []string { return []string{s} }
+	after := func(k string) string { return cursor{Sort: "id", Keys: []string{k}}.encode() }
+	before := func(k string) string { return cursor{Sort: "id", Keys: []string{k}, Before: true}.encode() }
+
+	tests := []struct {
+		name     string
+		items    []string
+		cursor   string
+		want     []string
+		wantNext string
+		wantPrev string
+	}{
+		{
+			name:     "first page with more",
+			items:    []string{"a", "b", "c"},
+			want:     []string{"a", "b"},
+			wantNext: after("b"),
+		},
+		{
+			name:  "only page",
+			items: []string{"a", "b"},
+			want:  []string{"a", "b"},
+		},
+		{
+			name:     "middle page",
+			items:    []string{"c", "d", "e"},
+			cursor:   after("b"),
+			want:     []string{"c", "d"},
+			wantNext: after("d"),
+			wantPrev: before("c"),
+		},
+		{
+			name:     "last page",
+			items:    []string{"e"},
+			cursor:   after("d"),
+			want:     []string{"e"},
+			wantPrev: before("e"),
+		},
+		{
+			name:     "page before, read backwards with more",
+			items:    []string{"d", "c", "b"},
+			cursor:   before("e"),
+			want:     []string{"c", "d"},
+			wantNext: after("d"),
+			wantPrev: before("c"),
+		},
+		{
+			name:     "first page reached backwards",
+			items:    []string{"b", "a"},
+			cursor:   before("c"),
+			want:     []string{"a", "b"},
+			wantNext: after("b"),
+		},
+		{
+			name:   "empty",
+			items:  []string{},
+			cursor: after("z"),
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Filters{Sort: "id", PageSize: 2, Keyset: true, Cursor: tt.cursor}
+
+			got, metadata := keysetPage(tt.items, f, keys)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got items %v, want %v", got, tt.want)
+			}
+			if metadata.NextCursor != tt.wantNext {
+				t.Errorf("got next cursor %q, want %q", metadata.NextCursor, tt.wantNext)
+			}
+			if metadata.PrevCursor != tt.wantPrev {
+				t.Errorf("got prev cursor %q, want %q", metadata.PrevCursor, tt.wantPrev)
+			}
+			if metadata.PageSize != 2 {
+				t.Errorf("got page size %d, want 2", metadata.PageSize)
+			}
+		})
+	}
+}
+
+func TestCursorQueryError(t *testing.T) {
+	withCursor := Filters{Sort: "id", Cursor: cursor{Sort: "id", Keys: []string{"not-a-uuid"}}.encode()}
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		err     error
+		filters Filters
+		want    error
+	}{
+		{"bad key", &pq.Error{Code: "22P02"}, withCursor, ErrInvalidCursor},
+		{"bad timestamp", &pq.Error{Code: "22007"}, withCursor, ErrInvalidCursor},
+		{"other postgres error", &pq.Error{Code: "42P01"}, withCursor, nil},
+		{"no cursor", &pq.Error{Code: "22P02"}, Filters{Sort: "id"}, nil},
+		{"not a postgres error", other, withCursor, other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cursorQueryError(tt.err, tt.filters)
+
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+
+			if !errors.Is(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
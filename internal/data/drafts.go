@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/lib/pq"
+)
+
+// ValidateSubmission checks that a draft is complete enough to be submitted
+// for review, applying the full rules of ValidateIdea. Drafts may be saved
+// without a PDF, but one must be attached before they are submitted.
+func ValidateSubmission(v *validator.Validator, idea *Idea, categoryExists bool) {
+	submitted := *idea
+	submitted.Status = IdeaStatusPending
+	submitted.PublishAt = nil
+
+	// "no key" is what the upload helper stored for a missing file
+	v.Check(idea.Pdf != "" && idea.Pdf != "no key", "pdf", "must be provided")
+
+	ValidateIdea(v, &submitted, categoryExists)
+}
+
+// GetDueDrafts returns up to limit drafts whose publish_at has passed, the
+// longest overdue first.
+func (i IdeaModel) GetDueDrafts(limit int) ([]*Idea, error) {
+	query := `SELECT id, created_at, updated_at, title, description, user_id, idea_source_id,
+                    COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+                    website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version, publish_at
+             FROM ideas
+             WHERE status = $1 AND publish_at <= NOW() AND deleted_at IS NULL
+             ORDER BY publish_at ASC
+             LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, IdeaStatusDraft, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ideas := []*Idea{}
+
+	for rows.Next() {
+		var idea Idea
+
+		err := rows.Scan(
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&idea.PublishAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		ideas = append(ideas, &idea)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ideas, nil
+}
+
+// Unschedule clears the idea's publish_at, for drafts that could not be
+// published when it came.
+func (i IdeaModel) Unschedule(idea *Idea) error {
+	query := `UPDATE ideas
+              SET publish_at = NULL, updated_at = NOW(), version = version + 1
+              WHERE id = $1 AND version = $2
+              RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := i.DB.QueryRowContext(ctx, query, idea.ID, idea.Version).Scan(&idea.UpdatedAt, &idea.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	idea.PublishAt = nil
+	return nil
+}
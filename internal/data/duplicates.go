@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DuplicateThreshold is the similarity above which an existing idea is
+// reported as a potential duplicate of a new one.
+const DuplicateThreshold = 0.3
+
+// maxDuplicates limits how many potential duplicates FindDuplicates returns.
+const maxDuplicates = 5
+
+// DuplicateIdea is an existing idea that resembles a new submission. The
+// similarity is a weighted mix of the title's trigram similarity, the share
+// of tags in common and the trigram similarity of the descriptions, each
+// between 0 and 1.
+type DuplicateIdea struct {
+	ID                    uuid.UUID `json:"id"`
+	Title                 string    `json:"title"`
+	Status                string    `json:"status"`
+	UserID                uuid.UUID `json:"user_id"`
+	Tags                  []string  `json:"tags"`
+	CreatedAt             time.Time `json:"created_at"`
+	Similarity            float64   `json:"similarity"`
+	TitleSimilarity       float64   `json:"title_similarity"`
+	TagOverlap            float64   `json:"tag_overlap"`
+	DescriptionSimilarity float64   `json:"description_similarity"`
+}
+
+// FindDuplicates returns the existing ideas most similar to the given one,
+// most similar first. Only public ideas and the author's own are compared, so
+// nothing is revealed that the author could not already see.
+func (i IdeaModel) FindDuplicates(idea *Idea) ([]*DuplicateIdea, error) {
+	query := `
+        WITH candidates AS (
+            SELECT id, title, status, user_id, tags, created_at,
+                   similarity(title, $1) AS title_similarity,
+                   COALESCE(
+                       cardinality(ARRAY(SELECT lower(t) FROM unnest(tags) t INTERSECT SELECT unnest($2::text[])))::float8
+                       / NULLIF(cardinality(ARRAY(SELECT lower(t) FROM unnest(tags) t UNION SELECT unnest($2::text[]))), 0),
+                   0) AS tag_overlap,
+                   similarity(description, $3) AS description_similarity
+            FROM ideas
+            WHERE (title % $1 OR tags && $4 OR description % $3)
+            AND (status = ANY($5) OR user_id = $6)
+            AND id <> $7
+            AND deleted_at IS NULL
+        )
+        SELECT id, title, status, user_id, tags, created_at,
+               0.5 * title_similarity + 0.2 * tag_overlap + 0.3 * description_similarity AS score,
+               title_similarity, tag_overlap, description_similarity
+        FROM candidates
+        WHERE 0.5 * title_similarity + 0.2 * tag_overlap + 0.3 * description_similarity >= $8
+        ORDER BY score DESC, created_at DESC
+        LIMIT $9`
+
+	lowerTags := make([]string, len(idea.Tags))
+	for n, tag := range idea.Tags {
+		lowerTags[n] = strings.ToLower(tag)
+	}
+
+	args := []any{
+		idea.Title,
+		pq.Array(lowerTags),
+		idea.Description,
+		pq.Array(append([]string{}, idea.Tags...)),
+		pq.Array(PublicIdeaStatuses),
+		idea.UserID,
+		idea.ID,
+		DuplicateThreshold,
+		maxDuplicates,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := []*DuplicateIdea{}
+
+	for rows.Next() {
+		var d DuplicateIdea
+
+		err := rows.Scan(
+			&d.ID,
+			&d.Title,
+			&d.Status,
+			&d.UserID,
+			pq.Array(&d.Tags),
+			&d.CreatedAt,
+			&d.Similarity,
+			&d.TitleSimilarity,
+			&d.TagOverlap,
+			&d.DescriptionSimilarity,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicates = append(duplicates, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Idea feeds.
+const (
+	FeedTrending = "trending"
+	FeedActivity = "activity"
+)
+
+// FeedPeriods are the windows a feed can be ranked over. They must match the
+// periods computed by the idea_activity_stats materialized view.
+var FeedPeriods = []string{"24h", "7d", "30d"}
+
+// feedWeights weigh the decayed views, edits and creation of an idea in the
+// score of each feed. Trending is driven by views; activity by ideas being
+// written and reworked, with views only breaking ties between them.
+var feedWeights = map[string]struct{ views, edits, creation float64 }{
+	FeedTrending: {views: 1, edits: 2, creation: 3},
+	FeedActivity: {views: 0.1, edits: 2, creation: 3},
+}
+
+// FeedEntry is an idea in a feed with the activity it was ranked on. Views
+// counts distinct viewers per day and Edits the revisions made, both within
+// the feed period.
+type FeedEntry struct {
+	Idea         *Idea     `json:"idea"`
+	Score        float64   `json:"score"`
+	Views        int       `json:"views"`
+	Edits        int       `json:"edits"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+// RecordView counts a view of the idea. Repeated views by the same viewer on
+// the same day are counted once.
+func (i IdeaModel) RecordView(ideaID uuid.UUID, viewer string) error {
+	query := `INSERT INTO idea_views (idea_id, viewer)
+	VALUES ($1, $2)
+	ON CONFLICT (idea_id, viewer, viewed_on) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := i.DB.ExecContext(ctx, query, ideaID, viewer)
+	return err
+}
+
+// RefreshActivityStats recomputes the idea_activity_stats view the feeds are
+// read from. Feeds keep being served from the previous data while it runs.
+func (i IdeaModel) RefreshActivityStats() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := i.DB.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY idea_activity_stats`)
+	return err
+}
+
+// GetFeed ranks the public ideas by their score in the given feed over the
+// period, as of the last refresh of the activity stats.
+func (i IdeaModel) GetFeed(feed, period string, filters Filters) ([]*FeedEntry, Metadata, error) {
+	weights, ok := feedWeights[feed]
+	if !ok {
+		return nil, Metadata{}, fmt.Errorf("unknown feed %q", feed)
+	}
+
+	score := fmt.Sprintf("(%g * s.decayed_views + %g * s.decayed_edits + %g * s.decayed_creation)",
+		weights.views, weights.edits, weights.creation)
+
+	query := fmt.Sprintf(`SELECT count(*) OVER(), i.id, i.created_at, i.updated_at, i.title, i.description,
+                                i.user_id, i.idea_source_id, COALESCE(i.category, ''), i.tags, i.status, i.learning_outcome,
+                                i.recommended_level, i.github_link, i.website_link, COALESCE(i.pdf, ''),
+                                i.upvotes, i.downvotes, i.votes, i.version,
+                                %s AS score, s.views, s.edits, s.last_active_at
+                          FROM idea_activity_stats s
+                          INNER JOIN ideas i ON i.id = s.idea_id AND i.deleted_at IS NULL
+                          WHERE s.period = $1
+                          AND i.status = ANY($2)
+                          AND %s > 0
+                          ORDER BY score DESC, s.last_active_at DESC, i.id ASC
+                          LIMIT $3 OFFSET $4`, score, score)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, period, pq.Array(PublicIdeaStatuses), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*FeedEntry{}
+
+	for rows.Next() {
+		var idea Idea
+		var entry FeedEntry
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&entry.Score,
+			&entry.Views,
+			&entry.Edits,
+			&entry.LastActiveAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entry.Idea = &idea
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
@@ -13,14 +13,22 @@ type Filters struct {
 	Sort         string
 	SortSafelist []string
 	SubmittedBy  string
+	// Keyset switches to cursor pagination: Page is ignored and the page
+	// starts after (or before) the row named by Cursor, or at the beginning
+	// when Cursor is empty.
+	Keyset bool
+	Cursor string
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int     `json:"current_page,omitempty"`
+	PageSize     int     `json:"page_size,omitempty"`
+	FirstPage    int     `json:"first_page,omitempty"`
+	LastPage     int     `json:"last_page,omitempty"`
+	TotalRecords int     `json:"total_records,omitempty"`
+	NextCursor   string  `json:"next_cursor,omitempty"`
+	PrevCursor   string  `json:"prev_cursor,omitempty"`
+	Facets       *Facets `json:"facets,omitempty"`
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -30,6 +38,15 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil {
+			v.AddError("cursor", "must be a cursor returned by a previous page")
+		} else {
+			v.Check(c.Sort == f.Sort, "cursor", "was issued for a different sort order")
+		}
+	}
 }
 
 func (f Filters) sortColumn() string {
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxLineageDepth bounds how far GetLineage walks up the fork chain.
+const maxLineageDepth = 50
+
+// NewFork returns a copy of source owned by userID that records source as
+// its origin. Forks start out as drafts so the new owner can adapt them
+// before submitting them for review.
+func NewFork(source *Idea, userID uuid.UUID) *Idea {
+	sourceID := source.ID
+
+	tags := make([]string, len(source.Tags))
+	copy(tags, source.Tags)
+
+	return &Idea{
+		Title:            source.Title,
+		Description:      source.Description,
+		UserID:           userID,
+		IdeaSourceID:     &sourceID,
+		Category:         source.Category,
+		Tags:             tags,
+		Status:           IdeaStatusDraft,
+		LearningOutcome:  source.LearningOutcome,
+		RecommendedLevel: source.RecommendedLevel,
+		GitHubLink:       source.GitHubLink,
+		WebsiteLink:      source.WebsiteLink,
+	}
+}
+
+// GetForks lists the ideas forked directly from the given idea. When statuses
+// is non-empty only forks in one of those statuses are returned.
+func (i IdeaModel) GetForks(sourceID uuid.UUID, statuses []string, filters Filters) ([]*Idea, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, updated_at, title, description,
+                                user_id, idea_source_id, COALESCE(category, ''), tags, status, learning_outcome,
+                                recommended_level, github_link, website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version
+                          FROM ideas
+                          WHERE idea_source_id = $1 AND deleted_at IS NULL
+                          AND (status = ANY($2) OR cardinality($2::text[]) = 0)
+                          ORDER BY %s %s, id ASC
+                          LIMIT $3 OFFSET $4`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, sourceID, pq.Array(statuses), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ideas := []*Idea{}
+
+	for rows.Next() {
+		var idea Idea
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		ideas = append(ideas, &idea)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return ideas, metadata, nil
+}
+
+// GetLineage returns the ancestors of an idea, starting with the idea it was
+// forked from and ending with the original.
+func (i IdeaModel) GetLineage(idea *Idea) ([]*Idea, error) {
+	ancestors := []*Idea{}
+	if idea.IdeaSourceID == nil {
+		return ancestors, nil
+	}
+
+	query := `
+        WITH RECURSIVE lineage AS (
+            SELECT ideas.*, 1 AS depth
+            FROM ideas
+            WHERE id = $1
+            UNION ALL
+            SELECT parent.*, lineage.depth + 1
+            FROM ideas parent
+            INNER JOIN lineage ON parent.id = lineage.idea_source_id
+            WHERE lineage.depth < $2
+        )
+        SELECT id, created_at, updated_at, title, description, user_id, idea_source_id,
+               COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+               website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version
+        FROM lineage
+        WHERE deleted_at IS NULL
+        ORDER BY depth ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, *idea.IdeaSourceID, maxLineageDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ancestor Idea
+
+		err := rows.Scan(
+			&ancestor.ID,
+			&ancestor.CreatedAt,
+			&ancestor.UpdatedAt,
+			&ancestor.Title,
+			&ancestor.Description,
+			&ancestor.UserID,
+			&ancestor.IdeaSourceID,
+			&ancestor.Category,
+			pq.Array(&ancestor.Tags),
+			&ancestor.Status,
+			&ancestor.LearningOutcome,
+			&ancestor.RecommendedLevel,
+			&ancestor.GitHubLink,
+			&ancestor.WebsiteLink,
+			&ancestor.Pdf,
+			&ancestor.Upvotes,
+			&ancestor.Downvotes,
+			&ancestor.Votes,
+			&ancestor.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestors = append(ancestors, &ancestor)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ancestors, nil
+}
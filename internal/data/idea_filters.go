@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Facet names accepted by IdeaFilter.where as the condition to leave out.
+const (
+	facetCategory = "category"
+	facetTag      = "tag"
+	facetLevel    = "level"
+)
+
+// IdeaFilter narrows down the ideas returned by GetAllIdeas. Zero-valued
+// fields do not filter. Query is a full-text search in websearch syntax
+// ("quoted phrases", OR, -excluded) over title, tags, description and
+// learning outcome.
+type IdeaFilter struct {
+	Query             string
+	Title             string
+	Categories        []string
+	Tags              []string
+	Statuses          []string
+	RecommendedLevels []string
+	UserID            *uuid.UUID
+	CreatedAfter      *time.Time
+	CreatedBefore     *time.Time
+	HasGitHubLink     *bool
+}
+
+// FacetCount is the number of matching ideas that share a value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets breaks down the ideas matching a filter by category, tag and
+// recommended level.
+type Facets struct {
+	Categories []FacetCount `json:"categories"`
+	Tags       []FacetCount `json:"tags"`
+	Levels     []FacetCount `json:"recommended_levels"`
+}
+
+// where builds the WHERE clause for the filter, numbering placeholders from
+// $1; when Query is set it is always $1. The condition for the facet named by
+// exclude is left out so that facet counts show what selecting another value
+// would return.
+func (f IdeaFilter) where(exclude string) (string, []any) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []any{}
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Query != "" {
+		add("search_vector @@ websearch_to_tsquery('english', $%d)", f.Query)
+	}
+	if f.Title != "" {
+		// Matches the expression of ideas_title_idx
+		add("to_tsvector('english', title) @@ plainto_tsquery('english', $%d)", f.Title)
+	}
+	if len(f.Categories) > 0 && exclude != facetCategory {
+		add("category = ANY($%d)", pq.Array(f.Categories))
+	}
+	if len(f.Tags) > 0 && exclude != facetTag {
+		add("tags @> $%d", pq.Array(f.Tags))
+	}
+	if len(f.Statuses) > 0 {
+		add("status = ANY($%d)", pq.Array(f.Statuses))
+	}
+	if len(f.RecommendedLevels) > 0 && exclude != facetLevel {
+		add("recommended_level = ANY($%d)", pq.Array(f.RecommendedLevels))
+	}
+	if f.UserID != nil {
+		add("user_id = $%d", *f.UserID)
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at < $%d", *f.CreatedBefore)
+	}
+	if f.HasGitHubLink != nil {
+		if *f.HasGitHubLink {
+			conditions = append(conditions, "COALESCE(github_link, '') <> ''")
+		} else {
+			conditions = append(conditions, "COALESCE(github_link, '') = ''")
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// maxTagFacets limits how many of the most used tags GetFacets returns.
+const maxTagFacets = 30
+
+// GetFacets counts the ideas matching the filter per category, tag and
+// recommended level, most common first.
+func (i IdeaModel) GetFacets(filter IdeaFilter) (*Facets, error) {
+	facets := &Facets{}
+
+	where, args := filter.where(facetCategory)
+	query := fmt.Sprintf(`SELECT category, count(*) FROM ideas WHERE %s
+	                      GROUP BY category ORDER BY count(*) DESC, category ASC`, where)
+
+	var err error
+	facets.Categories, err = i.countFacet(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args = filter.where(facetTag)
+	query = fmt.Sprintf(`SELECT tag, count(*) FROM ideas, unnest(tags) AS tag WHERE %s
+	                     GROUP BY tag ORDER BY count(*) DESC, tag ASC LIMIT %d`, where, maxTagFacets)
+
+	facets.Tags, err = i.countFacet(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args = filter.where(facetLevel)
+	query = fmt.Sprintf(`SELECT recommended_level, count(*) FROM ideas
+	                     WHERE %s AND COALESCE(recommended_level, '') <> ''
+	                     GROUP BY recommended_level ORDER BY count(*) DESC, recommended_level ASC`, where)
+
+	facets.Levels, err = i.countFacet(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+func (i IdeaModel) countFacet(query string, args []any) ([]FacetCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []FacetCount{}
+
+	for rows.Next() {
+		var fc FacetCount
+
+		if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, fc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	IdeaStatusDraft      = "draft"
+	IdeaStatusPending    = "pending"
+	IdeaStatusApproved   = "approved"
+	IdeaStatusRejected   = "rejected"
+	IdeaStatusInProgress = "in_progress"
+	IdeaStatusCompleted  = "completed"
+	IdeaStatusArchived   = "archived"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+var IdeaStatuses = []string{
+	IdeaStatusDraft,
+	IdeaStatusPending,
+	IdeaStatusApproved,
+	IdeaStatusRejected,
+	IdeaStatusInProgress,
+	IdeaStatusCompleted,
+	IdeaStatusArchived,
+}
+
+// PublicIdeaStatuses are the statuses of ideas that have passed moderation
+// and are visible to everyone.
+var PublicIdeaStatuses = []string{
+	IdeaStatusApproved,
+	IdeaStatusInProgress,
+	IdeaStatusCompleted,
+}
+
+// ideaTransitions lists, for each status, the statuses an idea may move to next.
+var ideaTransitions = map[string][]string{
+	IdeaStatusDraft:      {IdeaStatusPending},
+	IdeaStatusPending:    {IdeaStatusApproved, IdeaStatusRejected, IdeaStatusDraft},
+	IdeaStatusApproved:   {IdeaStatusInProgress, IdeaStatusArchived},
+	IdeaStatusRejected:   {IdeaStatusDraft},
+	IdeaStatusInProgress: {IdeaStatusCompleted, IdeaStatusArchived},
+	IdeaStatusCompleted:  {IdeaStatusArchived},
+	IdeaStatusArchived:   {},
+}
+
+// CanTransition reports whether an idea in status from may move to status to.
+func CanTransition(from, to string) bool {
+	return validator.PermittedValue(to, ideaTransitions[from]...)
+}
+
+// IsModerationTransition reports whether the transition is a moderation
+// decision that only holders of ideas:moderate may make.
+func IsModerationTransition(from, to string) bool {
+	return from == IdeaStatusPending && (to == IdeaStatusApproved || to == IdeaStatusRejected)
+}
+
+// IsPublicStatus reports whether ideas in the given status are publicly visible.
+func IsPublicStatus(status string) bool {
+	return validator.PermittedValue(status, PublicIdeaStatuses...)
+}
+
+type StatusTransition struct {
+	ID         uuid.UUID  `json:"id"`
+	IdeaID     uuid.UUID  `json:"idea_id"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	Reason     string     `json:"reason,omitempty"`
+	ChangedBy  *uuid.UUID `json:"changed_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func ValidateStatusTransition(v *validator.Validator, t *StatusTransition) {
+	v.Check(validator.PermittedValue(t.ToStatus, IdeaStatuses...), "status", "invalid status value")
+	v.Check(len(t.Reason) <= 500, "reason", "must not be more than 500 bytes long")
+
+	if t.ToStatus == IdeaStatusRejected {
+		v.Check(t.Reason != "", "reason", "must be provided when rejecting an idea")
+	}
+}
+
+// Transition moves the idea to a new status and records the change. It returns
+// ErrInvalidTransition if the state machine does not allow the move and
+// ErrEditConflict if the idea was modified concurrently.
+func (i IdeaModel) Transition(idea *Idea, t *StatusTransition) error {
+	if !CanTransition(idea.Status, t.ToStatus) {
+		return ErrInvalidTransition
+	}
+
+	t.IdeaID = idea.ID
+	t.FromStatus = idea.Status
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotIdea(ctx, tx, idea.ID, idea.Version)
+	if err != nil {
+		return err
+	}
+
+	// A scheduled publication only applies while the idea is a draft
+	query := `UPDATE ideas
+              SET status = $1, publish_at = CASE WHEN $1 = 'draft' THEN publish_at END,
+                  updated_at = NOW(), version = version + 1
+              WHERE id = $2 AND version = $3 AND status = $4
+              RETURNING updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, t.ToStatus, idea.ID, idea.Version, idea.Status).Scan(&idea.UpdatedAt, &idea.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query = `INSERT INTO idea_status_transitions (idea_id, from_status, to_status, reason, changed_by)
+             VALUES ($1, $2, $3, $4, $5)
+             RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, t.IdeaID, t.FromStatus, t.ToStatus, t.Reason, t.ChangedBy).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	idea.Status = t.ToStatus
+	if idea.Status != IdeaStatusDraft {
+		idea.PublishAt = nil
+	}
+	return nil
+}
+
+// GetTransitions returns the status history of an idea, oldest first.
+func (i IdeaModel) GetTransitions(ideaID uuid.UUID) ([]*StatusTransition, error) {
+	query := `SELECT id, idea_id, from_status, to_status, reason, changed_by, created_at
+             FROM idea_status_transitions
+             WHERE idea_id = $1
+             ORDER BY created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, ideaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []*StatusTransition{}
+
+	for rows.Next() {
+		var t StatusTransition
+
+		err := rows.Scan(&t.ID, &t.IdeaID, &t.FromStatus, &t.ToStatus, &t.Reason, &t.ChangedBy, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		transitions = append(transitions, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
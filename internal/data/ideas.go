@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,36 +15,53 @@ import (
 )
 
 type Idea struct {
-	ID               uuid.UUID `json:"id"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	UserID           uuid.UUID `json:"user_id"`
-	IdeaSourceID     string `json:"idea_source_id,omitempty"`
-	Pdf              string    `json:"pdf"`
-	Category         string    `json:"category"`
-	Tags             []string  `json:"tags"`
-	Status           string    `json:"status"`
-	LearningOutcome  string    `json:"learning_outcome,omitempty"`
-	RecommendedLevel string    `json:"recommended_level,omitempty"`
-	GitHubLink       string    `json:"github_link,omitempty"`
-	WebsiteLink      string    `json:"website_link,omitempty"`
-	Version          int       `json:"version"`
+	ID               uuid.UUID     `json:"id"`
+	Title            string        `json:"title"`
+	Description      string        `json:"description"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+	UserID           uuid.UUID     `json:"user_id"`
+	IdeaSourceID     *uuid.UUID    `json:"idea_source_id,omitempty"`
+	Pdf              string        `json:"pdf"`
+	Category         string        `json:"category"`
+	Tags             []string      `json:"tags"`
+	Status           string        `json:"status"`
+	LearningOutcome  string        `json:"learning_outcome,omitempty"`
+	RecommendedLevel string        `json:"recommended_level,omitempty"`
+	GitHubLink       string        `json:"github_link,omitempty"`
+	WebsiteLink      string        `json:"website_link,omitempty"`
+	Upvotes          int           `json:"upvotes"`
+	Downvotes        int           `json:"downvotes"`
+	Votes            int           `json:"votes"`
+	UserVote         int           `json:"user_vote"`
+	IsBookmarked     bool          `json:"is_bookmarked"`
+	Relevance        float64       `json:"relevance,omitempty"`
+	Highlight        string        `json:"highlight,omitempty"`
+	PublishAt        *time.Time    `json:"publish_at,omitempty"`
+	DeletedAt        *time.Time    `json:"deleted_at,omitempty"`
+	Progress         *IdeaProgress `json:"progress,omitempty"`
+	Version          int           `json:"version"`
 }
 
-type Comment struct {
-	ID          uuid.UUID `json:"id"`           // Unique identifier for the comment
-	IdeaID      uuid.UUID `json:"idea_id"`      // ID of the idea the comment is related to
-	CommentedBy uuid.UUID `json:"commented_by"` // User ID of the person who made the comment
-	Content     string    `json:"content"`      // Content of the comment
-	CreatedAt   time.Time `json:"created_at"`   // Timestamp of when the comment was created
-}
-
-func ValidateIdea(v *validator.Validator, idea *Idea) {
+// ValidateIdea checks an idea's fields. categoryExists reports whether the
+// idea's category is a known one, see CategoryModel.Exists.
+//
+// Drafts are validated leniently so that half-written proposals can be saved:
+// only the title is required, and other fields are checked only when set. The
+// full rules apply once a draft is submitted for review.
+func ValidateIdea(v *validator.Validator, idea *Idea, categoryExists bool) {
 	v.Check(idea.Title != "", "title", "must be provided")
 	v.Check(len(idea.Title) <= 100, "title", "must not be more than 100 bytes long")
 
+	if idea.PublishAt != nil {
+		v.Check(idea.Status == IdeaStatusDraft, "publish_at", "can only be set on drafts")
+	}
+
+	if idea.Status == IdeaStatusDraft {
+		validateDraft(v, idea, categoryExists)
+		return
+	}
+
 	v.Check(idea.Description != "", "description", "must be provided")
 	v.Check(len(idea.Description) <= 1000, "description", "must not be more than 1000 bytes long")
 
@@ -63,7 +81,7 @@ func ValidateIdea(v *validator.Validator, idea *Idea) {
 	// }
 
 	v.Check(idea.Category != "", "category", "must be provided")
-	v.Check(len(idea.Category) <= 50, "category", "must not be more than 50 bytes long")
+	v.Check(idea.Category == "" || categoryExists, "category", "must be an existing category")
 
 	v.Check(idea.Tags != nil, "tags", "must be provided")
 	v.Check(len(idea.Tags) >= 1, "tags", "must contain at least one tag")
@@ -88,6 +106,19 @@ func ValidateIdea(v *validator.Validator, idea *Idea) {
 
 }
 
+func validateDraft(v *validator.Validator, idea *Idea, categoryExists bool) {
+	v.Check(len(idea.Description) <= 1000, "description", "must not be more than 1000 bytes long")
+	v.Check(idea.Category == "" || categoryExists, "category", "must be an existing category")
+	v.Check(validator.Unique(idea.Tags), "tags", "must not contain duplicate values")
+
+	if idea.GitHubLink != "" {
+		v.Check(IsValidURL(idea.GitHubLink), "github_link", "must be a valid URL")
+	}
+	if idea.WebsiteLink != "" {
+		v.Check(IsValidURL(idea.WebsiteLink), "website_link", "must be a valid URL")
+	}
+}
+
 func IsValidURL(str string) bool {
 	return len(str) > 0 && (strings.HasPrefix(str, "http://") || strings.HasPrefix(str, "https://"))
 }
@@ -101,13 +132,24 @@ type IdeaModel struct {
 	DB *sql.DB
 }
 
+// Insert stores a new idea. New ideas start out pending review unless they
+// are explicitly saved as drafts.
 func (i IdeaModel) Insert(idea *Idea) error {
+	if idea.Status == "" {
+		idea.Status = IdeaStatusPending
+	}
+
+	if idea.Status != IdeaStatusDraft && idea.Status != IdeaStatusPending {
+		return ErrInvalidTransition
+	}
+
+	if idea.Tags == nil {
+		idea.Tags = []string{}
+	}
 
-	Status := "pending"
-	
 	query := `INSERT INTO ideas (title, description, user_id, idea_source_id, category, tags,
-	learning_outcome, recommended_level, github_link, website_link, status)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
+	learning_outcome, recommended_level, github_link, website_link, status, pdf, publish_at)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13) 
 	RETURNING id, created_at, updated_at, version`
 
 	args := []any{
@@ -121,7 +163,9 @@ func (i IdeaModel) Insert(idea *Idea) error {
 		idea.RecommendedLevel,
 		idea.GitHubLink,
 		idea.WebsiteLink,
-		Status,
+		idea.Status,
+		idea.Pdf,
+		idea.PublishAt,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -131,13 +175,21 @@ func (i IdeaModel) Insert(idea *Idea) error {
 
 }
 
+// Update saves the editable fields of an idea. Status changes go through
+// Transition so that the workflow is enforced. The previous version of the
+// idea is kept in idea_revisions.
 func (i IdeaModel) Update(idea *Idea) error {
+	if idea.Tags == nil {
+		idea.Tags = []string{}
+	}
+
 	query := `UPDATE ideas 
               SET title = $1, description = $2, user_id = $3, idea_source_id = $4, 
-                  category = $5, tags = $6, learning_outcome = $7, recommended_level = $8,
-                  github_link = $9, website_link = $10, status = $11, version = version + 1 
-              WHERE id = $12 AND version = $13 
-              RETURNING version`
+                  category = NULLIF($5, ''), tags = $6, learning_outcome = $7, recommended_level = $8,
+                  github_link = $9, website_link = $10, pdf = $11, publish_at = $12,
+                  updated_at = NOW(), version = version + 1 
+              WHERE id = $13 AND version = $14 
+              RETURNING updated_at, version`
 
 	args := []any{
 		idea.Title,
@@ -150,7 +202,8 @@ func (i IdeaModel) Update(idea *Idea) error {
 		idea.RecommendedLevel,
 		idea.GitHubLink,
 		idea.WebsiteLink,
-		idea.Status,
+		idea.Pdf,
+		idea.PublishAt,
 		idea.ID,
 		idea.Version,
 	}
@@ -158,7 +211,18 @@ func (i IdeaModel) Update(idea *Idea) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := i.DB.QueryRowContext(ctx, query, args...).Scan(&idea.Version)
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotIdea(ctx, tx, idea.ID, idea.Version)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&idea.UpdatedAt, &idea.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -167,15 +231,16 @@ func (i IdeaModel) Update(idea *Idea) error {
 			return err
 		}
 	}
-	return nil
+
+	return tx.Commit()
 }
 
 func (i IdeaModel) Get(id uuid.UUID) (*Idea, error) {
 	query := `SELECT id, created_at, updated_at, title, description, user_id, idea_source_id, 
-                    category, tags, status, learning_outcome, recommended_level, github_link, 
-                    website_link, version 
+                    COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link, 
+                    website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version, publish_at 
              FROM ideas 
-             WHERE id = $1`
+             WHERE id = $1 AND deleted_at IS NULL`
 
 	var idea Idea
 
@@ -197,7 +262,12 @@ func (i IdeaModel) Get(id uuid.UUID) (*Idea, error) {
 		&idea.RecommendedLevel,
 		&idea.GitHubLink,
 		&idea.WebsiteLink,
+		&idea.Pdf,
+		&idea.Upvotes,
+		&idea.Downvotes,
+		&idea.Votes,
 		&idea.Version,
+		&idea.PublishAt,
 	)
 
 	if err != nil {
@@ -213,8 +283,10 @@ func (i IdeaModel) Get(id uuid.UUID) (*Idea, error) {
 
 }
 
+// Delete moves the idea to its owner's trash. It stays restorable until the
+// purge job removes it for good.
 func (i IdeaModel) Delete(id uuid.UUID) error {
-	query := `DELETE FROM ideas WHERE id = $1`
+	query := `UPDATE ideas SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -236,25 +308,69 @@ func (i IdeaModel) Delete(id uuid.UUID) error {
 	return nil
 }
 
-func (i IdeaModel) GetAllIdeas(title string, tags []string, filters Filters) ([]*Idea, Metadata, error) {
-	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, updated_at, title, description, 
-                                user_id, idea_source_id, category, tags, status, learning_outcome, 
-                                recommended_level, github_link, website_link, version
+// htmlEscapeSQL wraps the SQL text expression so that it escapes the same
+// characters as html.EscapeString.
+func htmlEscapeSQL(expr string) string {
+	return fmt.Sprintf(`replace(replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`, expr)
+}
+
+// GetAllIdeas lists the ideas matching the filter. When the filter has a
+// search query each idea carries its relevance and a highlighted snippet of
+// its description and learning outcome.
+func (i IdeaModel) GetAllIdeas(filter IdeaFilter, filters Filters) ([]*Idea, Metadata, error) {
+	where, args := filter.where("")
+
+	relevance, highlight := "0::real", "''"
+	if filter.Query != "" {
+		relevance = "ts_rank(search_vector, websearch_to_tsquery('english', $1))"
+		// The text is HTML-escaped before the <mark> tags are added, so the
+		// snippet can be rendered as HTML without running markup from the idea
+		highlight = `ts_headline('english', ` + htmlEscapeSQL("description || ' ' || COALESCE(learning_outcome, '')") + `,
+		                         websearch_to_tsquery('english', $1),
+		                         'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')`
+	}
+
+	total := "count(*) OVER()"
+	order := fmt.Sprintf("%s %s, id ASC", filters.sortColumn(), filters.sortDirection())
+	limit, offset := filters.limit(), filters.offset()
+
+	// Keyset pages skip the total count and fetch one extra row to tell
+	// whether another page follows
+	if filters.Keyset {
+		sortExpr := filters.sortColumn()
+		if sortExpr == "relevance" {
+			sortExpr = relevance
+		}
+
+		condition, orderBy, cursorArgs, err := filters.keyset([]string{sortExpr, "id"}, len(args)+1)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		where += " AND " + condition
+		args = append(args, cursorArgs...)
+		total, order = "0", orderBy
+		limit, offset = filters.PageSize+1, 0
+	}
+
+	query := fmt.Sprintf(`SELECT %s, id, created_at, updated_at, title, description, 
+                                user_id, idea_source_id, COALESCE(category, ''), tags, status, learning_outcome, 
+                                recommended_level, github_link, website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version,
+                                %s AS relevance, %s
                           FROM ideas 
-                          WHERE (to_tsvector('english', title) @@ plainto_tsquery('english', $1) OR $1 = '') 
-                          AND (tags @> $2 OR $2 = '{}') 
-                          ORDER BY %s %s, id ASC
-                          LIMIT $3 OFFSET $4`,
-		filters.sortColumn(), filters.sortDirection())
+                          WHERE %s
+                          ORDER BY %s
+                          LIMIT $%d OFFSET $%d`,
+		total, relevance, highlight, where, order, len(args)+1, len(args)+2)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{title, pq.Array(tags), filters.limit(), filters.offset()}
+	args = append(args, limit, offset)
 
 	rows, err := i.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, Metadata{}, err
+		return nil, Metadata{}, cursorQueryError(err, filters)
 	}
 	defer rows.Close()
 
@@ -280,7 +396,13 @@ func (i IdeaModel) GetAllIdeas(title string, tags []string, filters Filters) ([]
 			&idea.RecommendedLevel,
 			&idea.GitHubLink,
 			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
 			&idea.Version,
+			&idea.Relevance,
+			&idea.Highlight,
 		)
 
 		if err != nil {
@@ -295,51 +417,91 @@ func (i IdeaModel) GetAllIdeas(title string, tags []string, filters Filters) ([]
 		return nil, Metadata{}, err
 	}
 
+	if filters.Keyset {
+		ideas, metadata := keysetPage(ideas, filters, func(idea *Idea) []string {
+			return []string{idea.sortKey(filters.sortColumn()), idea.ID.String()}
+		})
+		return ideas, metadata, nil
+	}
+
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 
 	return ideas, metadata, nil
 }
 
-func (i IdeaModel) GetAllByUserID(userID uuid.UUID, limit, offset int) ([]*Idea, int, error) {
-	// Query to get total count
-	countQuery := `
-        SELECT COUNT(*) 
-        FROM ideas 
-        WHERE user_id = $1`
-
-	// Main query with pagination
-	query := `
-        SELECT id, created_at, updated_at, title, description, user_id, idea_source_id, 
-               category, tags, status, learning_outcome, recommended_level, github_link,
-               website_link, version
+// sortKey returns the idea's value for a sort column as text, for use in a
+// pagination cursor.
+func (idea *Idea) sortKey(column string) string {
+	switch column {
+	case "title":
+		return idea.Title
+	case "category":
+		return idea.Category
+	case "votes":
+		return strconv.Itoa(idea.Votes)
+	case "created_at":
+		return idea.CreatedAt.Format(time.RFC3339Nano)
+	case "relevance":
+		return strconv.FormatFloat(idea.Relevance, 'g', -1, 64)
+	default:
+		return idea.ID.String()
+	}
+}
+
+// GetAllByUserID returns the ideas a user owns or has joined as a team member.
+// If statuses is non-empty only ideas in one of those statuses are returned.
+func (i IdeaModel) GetAllByUserID(userID uuid.UUID, statuses []string, filters Filters) ([]*Idea, Metadata, error) {
+	where := `(user_id = $1
+	          OR id IN (SELECT idea_id FROM idea_team_members WHERE user_id = $1 AND status = 'accepted'))
+	          AND (cardinality($2::text[]) = 0 OR status = ANY($2))
+	          AND deleted_at IS NULL`
+	args := []any{userID, pq.Array(append([]string{}, statuses...))}
+
+	total := "count(*) OVER()"
+	order := fmt.Sprintf("%s %s, id ASC", filters.sortColumn(), filters.sortDirection())
+	limit, offset := filters.limit(), filters.offset()
+
+	if filters.Keyset {
+		condition, orderBy, cursorArgs, err := filters.keyset([]string{filters.sortColumn(), "id"}, len(args)+1)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		where += " AND " + condition
+		args = append(args, cursorArgs...)
+		total, order = "0", orderBy
+		limit, offset = filters.PageSize+1, 0
+	}
+
+	query := fmt.Sprintf(`
+        SELECT %s, id, created_at, updated_at, title, description, user_id, idea_source_id, 
+               COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+               website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version, publish_at
         FROM ideas
-        WHERE user_id = $1
-        ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`
+        WHERE %s
+        ORDER BY %s
+		LIMIT $%d OFFSET $%d`, total, where, order, len(args)+1, len(args)+2)
+
+	args = append(args, limit, offset)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var totalCount int
-	err := i.DB.QueryRowContext(ctx, countQuery, userID).Scan(&totalCount)
-
-	if err != nil {
-		return nil, 0, err
-	}
-
-	rows, err := i.DB.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := i.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, Metadata{}, cursorQueryError(err, filters)
 	}
 
 	defer rows.Close()
 
-	var ideas []*Idea
+	totalRecords := 0
+	ideas := []*Idea{}
 
 	for rows.Next() {
 		var idea Idea
 
 		err := rows.Scan(
+			&totalRecords,
 			&idea.ID,
 			&idea.CreatedAt,
 			&idea.UpdatedAt,
@@ -354,11 +516,16 @@ func (i IdeaModel) GetAllByUserID(userID uuid.UUID, limit, offset int) ([]*Idea,
 			&idea.RecommendedLevel,
 			&idea.GitHubLink,
 			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
 			&idea.Version,
+			&idea.PublishAt,
 		)
 
 		if err != nil {
-			return nil, 0, err
+			return nil, Metadata{}, err
 		}
 
 		ideas = append(ideas, &idea)
@@ -366,9 +533,15 @@ func (i IdeaModel) GetAllByUserID(userID uuid.UUID, limit, offset int) ([]*Idea,
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, Metadata{}, err
 	}
 
-	return ideas, totalCount, nil
+	if filters.Keyset {
+		ideas, metadata := keysetPage(ideas, filters, func(idea *Idea) []string {
+			return []string{idea.sortKey(filters.sortColumn()), idea.ID.String()}
+		})
+		return ideas, metadata, nil
+	}
 
+	return ideas, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
 }
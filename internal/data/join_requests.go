@@ -0,0 +1,265 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestAccepted = "accepted"
+	JoinRequestRejected = "rejected"
+)
+
+var ErrDuplicateJoinRequest = errors.New("duplicate join request")
+
+var JoinRequestStatuses = []string{JoinRequestPending, JoinRequestAccepted, JoinRequestRejected}
+
+type JoinRequest struct {
+	ID          uuid.UUID  `json:"id"`
+	IdeaID      uuid.UUID  `json:"idea_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	UserName    string     `json:"user_name"`
+	Message     string     `json:"message"`
+	Skills      []string   `json:"skills"`
+	Status      string     `json:"status"`
+	Response    string     `json:"response,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	Version     int        `json:"version"`
+}
+
+// ValidateJoinRequest checks the request's message and that every skill it
+// lists is one of the requester's profile skills.
+func ValidateJoinRequest(v *validator.Validator, req *JoinRequest, profileSkills []string) {
+	v.Check(req.Message != "", "message", "must be provided")
+	v.Check(len(req.Message) <= 1000, "message", "must not be more than 1000 bytes long")
+
+	v.Check(len(req.Skills) <= 20, "skills", "must not contain more than 20 skills")
+	v.Check(validator.Unique(req.Skills), "skills", "must not contain duplicate values")
+
+	for _, skill := range req.Skills {
+		v.Check(validator.PermittedValue(skill, profileSkills...), "skills", "must only contain skills listed on your profile")
+	}
+}
+
+type JoinRequestModel struct {
+	DB *sql.DB
+}
+
+func (m JoinRequestModel) Insert(req *JoinRequest) error {
+	query := `INSERT INTO join_requests (idea_id, user_id, message, skills)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, status, created_at, version`
+
+	args := []any{req.IdeaID, req.UserID, req.Message, pq.Array(req.Skills)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&req.ID, &req.Status, &req.CreatedAt, &req.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_join_requests_pending"`:
+			return ErrDuplicateJoinRequest
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m JoinRequestModel) Get(id uuid.UUID) (*JoinRequest, error) {
+	query := `SELECT j.id, j.idea_id, j.user_id, u.user_name, j.message, j.skills, j.status,
+	                 j.response, j.created_at, j.responded_at, j.version
+	          FROM join_requests j
+	          INNER JOIN users u ON u.id = j.user_id
+	          WHERE j.id = $1`
+
+	var req JoinRequest
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&req.ID,
+		&req.IdeaID,
+		&req.UserID,
+		&req.UserName,
+		&req.Message,
+		pq.Array(&req.Skills),
+		&req.Status,
+		&req.Response,
+		&req.CreatedAt,
+		&req.RespondedAt,
+		&req.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &req, nil
+}
+
+// GetAllForIdea lists the join requests for an idea, newest first. When
+// statuses is non-empty only requests in one of those statuses are returned.
+func (m JoinRequestModel) GetAllForIdea(ideaID uuid.UUID, statuses []string, filters Filters) ([]*JoinRequest, Metadata, error) {
+	query := `SELECT count(*) OVER(), j.id, j.idea_id, j.user_id, u.user_name, j.message, j.skills,
+	                 j.status, j.response, j.created_at, j.responded_at, j.version
+	          FROM join_requests j
+	          INNER JOIN users u ON u.id = j.user_id
+	          WHERE j.idea_id = $1
+	          AND (j.status = ANY($2) OR cardinality($2::text[]) = 0)
+	          ORDER BY j.created_at DESC, j.id ASC
+	          LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID, pq.Array(statuses), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	requests := []*JoinRequest{}
+
+	for rows.Next() {
+		var req JoinRequest
+
+		err := rows.Scan(
+			&totalRecords,
+			&req.ID,
+			&req.IdeaID,
+			&req.UserID,
+			&req.UserName,
+			&req.Message,
+			pq.Array(&req.Skills),
+			&req.Status,
+			&req.Response,
+			&req.CreatedAt,
+			&req.RespondedAt,
+			&req.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		requests = append(requests, &req)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return requests, metadata, nil
+}
+
+// Accept marks a pending request as accepted and adds the requester to the
+// idea's team as a member. It returns ErrTeamFull if the team has no room and
+// ErrEditConflict if the request was answered concurrently.
+func (m JoinRequestModel) Accept(req *JoinRequest, maxSize int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the idea so concurrent acceptances cannot overshoot the cap. An
+	// outstanding invitation for the requester already holds a place.
+	var size int
+	query := `SELECT 1 + (SELECT count(*) FROM idea_team_members
+	                      WHERE idea_id = ideas.id AND user_id <> $2 AND status IN ('invited', 'accepted'))
+	          FROM ideas
+	          WHERE id = $1 AND deleted_at IS NULL
+	          FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, req.IdeaID, req.UserID).Scan(&size)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if size >= maxSize {
+		return ErrTeamFull
+	}
+
+	query = `INSERT INTO idea_team_members (idea_id, user_id, role, status, responded_at)
+	         VALUES ($1, $2, $3, 'accepted', NOW())
+	         ON CONFLICT (idea_id, user_id) DO UPDATE
+	         SET status = 'accepted', responded_at = NOW(), version = idea_team_members.version + 1
+	         WHERE idea_team_members.status <> 'accepted'`
+
+	_, err = tx.ExecContext(ctx, query, req.IdeaID, req.UserID, TeamRoleMember)
+	if err != nil {
+		return err
+	}
+
+	err = respondToJoinRequest(ctx, tx, req, JoinRequestAccepted)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reject marks a pending request as rejected.
+func (m JoinRequestModel) Reject(req *JoinRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = respondToJoinRequest(ctx, tx, req, JoinRequestRejected)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func respondToJoinRequest(ctx context.Context, tx *sql.Tx, req *JoinRequest, status string) error {
+	query := `UPDATE join_requests
+	          SET status = $1, response = $2, responded_at = NOW(), version = version + 1
+	          WHERE id = $3 AND version = $4 AND status = 'pending'
+	          RETURNING responded_at, version`
+
+	err := tx.QueryRowContext(ctx, query, status, req.Response, req.ID, req.Version).Scan(&req.RespondedAt, &req.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	req.Status = status
+	return nil
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LoginStatus sums up the recent failed logins for an email address and for
+// the IP address a login comes from
+type LoginStatus struct {
+	AccountFailures int
+	LastFailure     *time.Time
+	IPFailures      int
+	LockedUntil     *time.Time
+}
+
+type LoginFailureModel struct {
+	DB *sql.DB
+}
+
+// GetStatus counts the failed logins for the email and the IP address since
+// the given time, and reports whether the email is locked out
+func (m LoginFailureModel) GetStatus(email, ip string, since time.Time) (*LoginStatus, error) {
+	query := `SELECT (SELECT count(*) FROM login_failures WHERE email = $1 AND created_at > $3),
+	                 (SELECT max(created_at) FROM login_failures WHERE email = $1 AND created_at > $3),
+	                 (SELECT count(*) FROM login_failures WHERE ip = $2 AND created_at > $3),
+	                 (SELECT locked_until FROM account_lockouts WHERE email = $1 AND locked_until > NOW())`
+
+	var status LoginStatus
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, email, ip, since).Scan(
+		&status.AccountFailures,
+		&status.LastFailure,
+		&status.IPFailures,
+		&status.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Record stores a failed login and returns the number of failures for the
+// email since the given time, this one included
+func (m LoginFailureModel) Record(email, ip string, since time.Time) (int, error) {
+	query := `WITH inserted AS (
+	              INSERT INTO login_failures (email, ip) VALUES ($1, $2)
+	          )
+	          SELECT count(*) + 1 FROM login_failures WHERE email = $1 AND created_at > $3`
+
+	var failures int
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, email, ip, since).Scan(&failures)
+
+	return failures, err
+}
+
+// Lock refuses logins for the email until the given time. It reports false,
+// leaving the lockout as it is, when the email is already locked, so that
+// concurrent failures start only one lockout.
+func (m LoginFailureModel) Lock(email string, until time.Time) (bool, error) {
+	query := `INSERT INTO account_lockouts (email, locked_until)
+	          VALUES ($1, $2)
+	          ON CONFLICT (email) DO UPDATE SET locked_until = EXCLUDED.locked_until, created_at = NOW()
+	          WHERE account_lockouts.locked_until <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, email, until)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Clear forgets the failed logins for the email and lifts its lockout
+func (m LoginFailureModel) Clear(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM login_failures WHERE email = $1`, email)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM account_lockouts WHERE email = $1`, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpired removes failed logins older than the given time and lockouts
+// that have ended
+func (m LoginFailureModel) DeleteExpired(before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM login_failures WHERE created_at <= $1`, before)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, `DELETE FROM account_lockouts WHERE locked_until <= NOW()`)
+
+	return err
+}
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	MilestoneStatusOpen      = "open"
+	MilestoneStatusCompleted = "completed"
+)
+
+var MilestoneStatuses = []string{MilestoneStatusOpen, MilestoneStatusCompleted}
+
+// Milestone is a goal on the way to completing an idea. TasksTotal and
+// TasksDone count the tasks attached to it.
+type Milestone struct {
+	ID          uuid.UUID  `json:"id"`
+	IdeaID      uuid.UUID  `json:"idea_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Status      string     `json:"status"`
+	TasksTotal  int        `json:"tasks_total"`
+	TasksDone   int        `json:"tasks_done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Version     int        `json:"version"`
+}
+
+func ValidateMilestone(v *validator.Validator, milestone *Milestone) {
+	v.Check(milestone.Title != "", "title", "must be provided")
+	v.Check(len(milestone.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(len(milestone.Description) <= 2000, "description", "must not be more than 2000 bytes long")
+	v.Check(validator.PermittedValue(milestone.Status, MilestoneStatuses...), "status", "must be open or completed")
+}
+
+type MilestoneModel struct {
+	DB *sql.DB
+}
+
+func (m MilestoneModel) Insert(milestone *Milestone) error {
+	query := `INSERT INTO idea_milestones (idea_id, title, description, due_date, status)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at, version`
+
+	args := []any{milestone.IdeaID, milestone.Title, milestone.Description, milestone.DueDate, milestone.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&milestone.ID, &milestone.CreatedAt, &milestone.UpdatedAt, &milestone.Version)
+}
+
+func (m MilestoneModel) Get(id uuid.UUID) (*Milestone, error) {
+	query := `SELECT id, idea_id, title, description, due_date, status,
+	                 (SELECT count(*) FROM idea_tasks t WHERE t.milestone_id = ms.id),
+	                 (SELECT count(*) FROM idea_tasks t WHERE t.milestone_id = ms.id AND t.status = 'done'),
+	                 created_at, updated_at, version
+	          FROM idea_milestones ms
+	          WHERE id = $1`
+
+	var milestone Milestone
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&milestone.ID,
+		&milestone.IdeaID,
+		&milestone.Title,
+		&milestone.Description,
+		&milestone.DueDate,
+		&milestone.Status,
+		&milestone.TasksTotal,
+		&milestone.TasksDone,
+		&milestone.CreatedAt,
+		&milestone.UpdatedAt,
+		&milestone.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &milestone, nil
+}
+
+func (m MilestoneModel) Update(milestone *Milestone) error {
+	query := `UPDATE idea_milestones
+	          SET title = $1, description = $2, due_date = $3, status = $4, updated_at = NOW(), version = version + 1
+	          WHERE id = $5 AND version = $6
+	          RETURNING updated_at, version`
+
+	args := []any{
+		milestone.Title,
+		milestone.Description,
+		milestone.DueDate,
+		milestone.Status,
+		milestone.ID,
+		milestone.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&milestone.UpdatedAt, &milestone.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a milestone. Its tasks stay on the board without one.
+func (m MilestoneModel) Delete(id uuid.UUID) error {
+	query := `DELETE FROM idea_milestones WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForIdea returns the idea's milestones by due date, those without one
+// last.
+func (m MilestoneModel) GetAllForIdea(ideaID uuid.UUID) ([]*Milestone, error) {
+	query := `SELECT id, idea_id, title, description, due_date, status,
+	                 (SELECT count(*) FROM idea_tasks t WHERE t.milestone_id = ms.id),
+	                 (SELECT count(*) FROM idea_tasks t WHERE t.milestone_id = ms.id AND t.status = 'done'),
+	                 created_at, updated_at, version
+	          FROM idea_milestones ms
+	          WHERE idea_id = $1
+	          ORDER BY due_date ASC NULLS LAST, created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	milestones := []*Milestone{}
+
+	for rows.Next() {
+		var milestone Milestone
+
+		err := rows.Scan(
+			&milestone.ID,
+			&milestone.IdeaID,
+			&milestone.Title,
+			&milestone.Description,
+			&milestone.DueDate,
+			&milestone.Status,
+			&milestone.TasksTotal,
+			&milestone.TasksDone,
+			&milestone.CreatedAt,
+			&milestone.UpdatedAt,
+			&milestone.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		milestones = append(milestones, &milestone)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return milestones, nil
+}
@@ -11,19 +11,43 @@ var (
 )
 
 type Models struct {
-	Ideas       IdeaModel
-	Permissions PermissionModel
-	Users       UserModal
-	UserProfile ProfileModel
-	Tokens      TokenModel
+	Ideas         IdeaModel
+	Permissions   PermissionModel
+	Users         UserModal
+	UserProfile   ProfileModel
+	Tokens        TokenModel
+	Comments      CommentModel
+	Votes         VoteModel
+	Revisions     RevisionModel
+	Teams         TeamModel
+	JoinRequests  JoinRequestModel
+	Bookmarks     BookmarkModel
+	Collections   CollectionModel
+	Categories    CategoryModel
+	Milestones    MilestoneModel
+	Tasks         TaskModel
+	TwoFactor     TwoFactorModel
+	LoginFailures LoginFailureModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Ideas:       IdeaModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Users:       UserModal{DB: db},
-		UserProfile: ProfileModel{DB: db},
-		Tokens:      TokenModel{DB: db},
+		Ideas:         IdeaModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		Users:         UserModal{DB: db},
+		UserProfile:   ProfileModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Comments:      CommentModel{DB: db},
+		Votes:         VoteModel{DB: db},
+		Revisions:     RevisionModel{DB: db},
+		Teams:         TeamModel{DB: db},
+		JoinRequests:  JoinRequestModel{DB: db},
+		Bookmarks:     BookmarkModel{DB: db},
+		Collections:   CollectionModel{DB: db},
+		Categories:    CategoryModel{DB: db},
+		Milestones:    MilestoneModel{DB: db},
+		Tasks:         TaskModel{DB: db},
+		TwoFactor:     TwoFactorModel{DB: db},
+		LoginFailures: LoginFailureModel{DB: db},
 	}
 }
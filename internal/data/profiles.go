@@ -4,38 +4,46 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+// ErrDuplicateProfile is returned when a profile is created for a user who
+// still has one, such as a deleted profile the purge job has not removed yet.
+var ErrDuplicateProfile = errors.New("duplicate profile")
+
 type Profile struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Firstname string    `json:"firstname,omitempty"`
-	Lastname  string    `json:"lastname,omitempty"`
-	Avatar    string    `json:"avatar,omitempty"`
-	AvatarURL string    `json:"avatar_url,omitempty"`
-	Title     string    `json:"title,omitempty"`
-	Bio       string    `json:"bio,omitempty"`
-	Faculty   string    `json:"faculty,omitempty"`
-	Program   string    `json:"program,omitempty"`
-	Degree    string    `json:"degree,omitempty"`
-	Year      string    `json:"year,omitempty"`
-	Uni       string    `json:"uni,omitempty"`
-	Mobile    string    `json:"mobile,omitempty"`
-	LinkedIn  string    `json:"linkedin,omitempty"`
-	GitHub    string    `json:"github,omitempty"`
-	FB        string    `json:"fb,omitempty"`
-	Skills    []string  `json:"skills"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Firstname string     `json:"firstname,omitempty"`
+	Lastname  string     `json:"lastname,omitempty"`
+	Avatar    string     `json:"avatar,omitempty"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Bio       string     `json:"bio,omitempty"`
+	Faculty   string     `json:"faculty,omitempty"`
+	Program   string     `json:"program,omitempty"`
+	Degree    string     `json:"degree,omitempty"`
+	Year      string     `json:"year,omitempty"`
+	Uni       string     `json:"uni,omitempty"`
+	Mobile    string     `json:"mobile,omitempty"`
+	LinkedIn  string     `json:"linkedin,omitempty"`
+	GitHub    string     `json:"github,omitempty"`
+	FB        string     `json:"fb,omitempty"`
+	Skills    []string   `json:"skills"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type UserProfile struct {
 	ID                  uuid.UUID `json:"id"`
 	Username            string    `json:"username"`
-	Email               string    `json:"email"`
+	Email               string    `json:"email,omitempty"`
 	UserType            string    `json:"user_type"`
 	Firstname           string    `json:"firstname,omitempty"`
 	Lastname            string    `json:"lastname,omitempty"`
@@ -54,6 +62,7 @@ type UserProfile struct {
 	FB                  string    `json:"fb,omitempty"`
 	Skills              []string  `json:"skills"`
 	HasCompletedProfile bool      `json:"has_completed_profile"`
+	Relevance           float64   `json:"relevance,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
@@ -75,8 +84,8 @@ func (m ProfileModel) GetAllProfilesWithIdeas(limit int, offset int) ([]*UserPro
                p.faculty, p.program, p.degree, p.year, p.uni, p.mobile, 
                p.linkedin, p.github, p.fb, p.updated_at
         FROM users u
-        LEFT JOIN user_profiles p ON u.id = p.user_id
-		WHERE u.has_profile_created = true
+        LEFT JOIN user_profiles p ON u.id = p.user_id AND p.deleted_at IS NULL
+		WHERE u.has_profile_created = true AND u.deleted_at IS NULL
         ORDER BY u.created_at DESC
         LIMIT $1 OFFSET $2`
 
@@ -209,13 +218,15 @@ func (m ProfileModel) GetAllProfilesWithIdeas(limit int, offset int) ([]*UserPro
 
 		profile.Skills = skills
 
-		// Get ideas submitted by the user
+		// Get ideas submitted by the user or whose team they have joined
 		ideasQuery := `
     SELECT id, created_at, updated_at, title, description, user_id, idea_source_id,
-           category, tags, status, learning_outcome, recommended_level, github_link,
-           website_link, version
+           COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+           website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version
     FROM ideas
-    WHERE user_id = $1
+    WHERE (user_id = $1
+    OR id IN (SELECT idea_id FROM idea_team_members WHERE user_id = $1 AND status = 'accepted'))
+    AND deleted_at IS NULL
     ORDER BY created_at DESC
 `
 		ideaRows, err := m.DB.QueryContext(ctx, ideasQuery, profile.ID)
@@ -246,6 +257,10 @@ func (m ProfileModel) GetAllProfilesWithIdeas(limit int, offset int) ([]*UserPro
 				&idea.RecommendedLevel,
 				&idea.GitHubLink,
 				&idea.WebsiteLink,
+				&idea.Pdf,
+				&idea.Upvotes,
+				&idea.Downvotes,
+				&idea.Votes,
 				&idea.Version,
 			)
 			if err != nil {
@@ -254,7 +269,6 @@ func (m ProfileModel) GetAllProfilesWithIdeas(limit int, offset int) ([]*UserPro
 			}
 
 			idea.Tags = tags
-			idea.UserID = profile.ID
 			ideas = append(ideas, &idea)
 		}
 		ideaRows.Close()
@@ -281,7 +295,7 @@ func (m ProfileModel) GetByUserID(userID uuid.UUID) (*Profile, error) {
 		       program, degree, year, uni, mobile, linkedin, github, fb, 
 		       created_at, updated_at
 		FROM user_profiles
-		WHERE user_id = $1`
+		WHERE user_id = $1 AND deleted_at IS NULL`
 
 	var profile Profile
 
@@ -359,8 +373,8 @@ func (m ProfileModel) GetUserProfile(userID uuid.UUID) (*UserProfile, error) {
 		       p.faculty, p.program, p.degree, p.year, p.uni, p.mobile, 
 		       p.linkedin, p.github, p.fb, p.updated_at
 		FROM users u
-		LEFT JOIN user_profiles p ON u.id = p.user_id
-		WHERE u.id = $1`
+		LEFT JOIN user_profiles p ON u.id = p.user_id AND p.deleted_at IS NULL
+		WHERE u.id = $1 AND u.deleted_at IS NULL`
 
 	var profile UserProfile
 	var hasProfileCreated bool
@@ -498,8 +512,8 @@ func (m ProfileModel) GetFullProfile(userID uuid.UUID) (*UserProfile, error) {
 		       p.faculty, p.program, p.degree, p.year, p.uni, p.mobile, 
 		       p.linkedin, p.github, p.fb, p.updated_at
 		FROM users u
-		LEFT JOIN user_profiles p ON u.id = p.user_id
-		WHERE u.has_profile_created = true AND u.id = $1`
+		LEFT JOIN user_profiles p ON u.id = p.user_id AND p.deleted_at IS NULL
+		WHERE u.has_profile_created = true AND u.id = $1 AND u.deleted_at IS NULL`
 
 	var profile UserProfile
 	var hasProfileCreated bool
@@ -642,8 +656,8 @@ func (m ProfileModel) GetByUsername(username string) (*UserProfile, error) {
 		       p.faculty, p.program, p.degree, p.year, p.uni, p.mobile, 
 		       p.linkedin, p.github, p.fb, p.updated_at
 		FROM users u
-		LEFT JOIN user_profiles p ON u.id = p.user_id
-		WHERE u.user_name = $1`
+		LEFT JOIN user_profiles p ON u.id = p.user_id AND p.deleted_at IS NULL
+		WHERE u.user_name = $1 AND u.deleted_at IS NULL`
 
 	var profile UserProfile
 	var firstname, lastname, avatar, title, bio sql.NullString
@@ -812,7 +826,12 @@ func (m ProfileModel) Insert(profile *Profile) error {
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&profile.CreatedAt, &profile.UpdatedAt)
 	if err != nil {
-		return err
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "user_profiles_pkey"`:
+			return ErrDuplicateProfile
+		default:
+			return err
+		}
 	}
 
 	// Insert skills
@@ -832,7 +851,7 @@ func (m ProfileModel) Update(profile *Profile) error {
 		SET firstname = $1, lastname = $2, avatar = $3, title = $4, bio = $5,
 		    faculty = $6, program = $7, degree = $8, year=$9, uni = $10, mobile = $11,
 		    linkedin = $12, github = $13, fb = $14,  updated_at = NOW()
-		WHERE user_id = $15
+		WHERE user_id = $15 AND deleted_at IS NULL
 		RETURNING updated_at`
 
 	args := []any{
@@ -912,52 +931,155 @@ func (m ProfileModel) UpdateSkills(userID uuid.UUID, skills []string) error {
 	return m.updateSkills(userID, skills)
 }
 
-func (m ProfileModel) Search(query string, limit int, offset int) ([]*UserProfile, error) {
-	sqlQuery := `
-		SELECT u.id, u.user_name, u.email, u.user_type, u.created_at,
-		       p.firstname, p.lastname, p.avatar, p.title, p.bio, 
-		       p.faculty, p.program, p.degree, p.year, p.uni, p.mobile, 
-		       p.linkedin, p.github, p.fb, p.updated_at
+// GetSkills returns the skills a user has listed on their profile.
+func (m ProfileModel) GetSkills(userID uuid.UUID) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, "SELECT skill FROM user_skills WHERE user_id = $1 ORDER BY skill", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := []string{}
+	for rows.Next() {
+		var skill string
+		if err := rows.Scan(&skill); err != nil {
+			return nil, err
+		}
+		skills = append(skills, skill)
+	}
+
+	return skills, rows.Err()
+}
+
+// ProfileFilter narrows down the profiles returned by Search. Empty fields do
+// not filter; the others must match exactly, ignoring case.
+type ProfileFilter struct {
+	Faculty string
+	Program string
+	Degree  string
+	Year    string
+	Uni     string
+}
+
+// profileSimilarityThreshold is the lowest word similarity between the search
+// term and a profile field that still counts as a match.
+const profileSimilarityThreshold = "0.3"
+
+// Search finds profiles whose username, name, title, bio, faculty, program or
+// skills are similar to the query, tolerating typos and partial words. Results
+// are ranked by how closely they match, with usernames and names weighted
+// above the other fields. An empty query lists every profile the filter lets
+// through, newest first. Results leave out email addresses and phone numbers,
+// since any user can search.
+func (m ProfileModel) Search(query string, filter ProfileFilter, filters Filters) ([]*UserProfile, Metadata, error) {
+	conditions := []string{"u.deleted_at IS NULL"}
+	args := []any{}
+
+	if query != "" {
+		args = append(args, query)
+		conditions = append(conditions, `($1 <% u.user_name
+		    OR $1 <% (COALESCE(p.firstname, '') || ' ' || COALESCE(p.lastname, ''))
+		    OR $1 <% p.title
+		    OR $1 <% p.bio
+		    OR $1 <% p.faculty
+		    OR $1 <% p.program
+		    OR EXISTS (SELECT 1 FROM user_skills s WHERE s.user_id = u.id AND $1 <% s.skill))`)
+	}
+
+	for _, f := range []struct{ column, value string }{
+		{"p.faculty", filter.Faculty},
+		{"p.program", filter.Program},
+		{"p.degree", filter.Degree},
+		{"p.year", filter.Year},
+		{"p.uni", filter.Uni},
+	} {
+		if f.value != "" {
+			args = append(args, f.value)
+			conditions = append(conditions, fmt.Sprintf("lower(%s) = lower($%d)", f.column, len(args)))
+		}
+	}
+
+	score := "0::float8"
+	if query != "" {
+		score = `GREATEST(
+		        word_similarity($1, u.user_name),
+		        word_similarity($1, COALESCE(p.firstname, '') || ' ' || COALESCE(p.lastname, '')),
+		        0.8 * COALESCE((SELECT max(word_similarity($1, s.skill)) FROM user_skills s WHERE s.user_id = u.id), 0),
+		        0.7 * word_similarity($1, COALESCE(p.title, '')),
+		        0.6 * GREATEST(word_similarity($1, COALESCE(p.faculty, '')), word_similarity($1, COALESCE(p.program, ''))),
+		        0.5 * word_similarity($1, COALESCE(p.bio, ''))
+		    )::float8`
+	}
+
+	total := "count(*) OVER()"
+	order := "score DESC, u.created_at DESC, u.id DESC"
+	limit, offset := filters.limit(), filters.offset()
+
+	if filters.Keyset {
+		condition, orderBy, cursorArgs, err := filters.keyset([]string{score, "u.created_at", "u.id"}, len(args)+1)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+		total, order = "0", orderBy
+		limit, offset = filters.PageSize+1, 0
+	}
+
+	args = append(args, limit, offset)
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, u.id, u.user_name, u.user_type, u.created_at,
+		       p.firstname, p.lastname, p.avatar, p.title, p.bio,
+		       p.faculty, p.program, p.degree, p.year, p.uni,
+		       p.linkedin, p.github, p.fb, p.updated_at,
+		       ARRAY(SELECT skill FROM user_skills WHERE user_id = u.id ORDER BY skill),
+		       %s AS score
 		FROM users u
-		LEFT JOIN user_profiles p ON u.id = p.user_id
-		WHERE u.user_name ILIKE $1
-		   OR p.firstname ILIKE $1
-		   OR p.lastname ILIKE $1
-		   OR p.title ILIKE $1
-		   OR p.bio ILIKE $1
-		   OR p.faculty ILIKE $1
-		   OR p.program ILIKE $1
-		ORDER BY 
-		    CASE WHEN u.user_name ILIKE $1 THEN 1
-		         WHEN p.firstname ILIKE $1 OR p.lastname ILIKE $1 THEN 2
-		         ELSE 3
-		    END,
-		    u.created_at DESC
-		LIMIT $2 OFFSET $3`
+		LEFT JOIN user_profiles p ON u.id = p.user_id AND p.deleted_at IS NULL
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, total, score, strings.Join(conditions, " AND "), order, len(args)-1, len(args))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	searchTerm := "%" + query + "%"
-	rows, err := m.DB.QueryContext(ctx, sqlQuery, searchTerm, limit, offset)
+	// The threshold used by the <% operator is set for this transaction only
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
-		return nil, err
+		return nil, Metadata{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, profileSimilarityThreshold)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, Metadata{}, cursorQueryError(err, filters)
 	}
 	defer rows.Close()
 
+	totalRecords := 0
 	profiles := []*UserProfile{}
 
 	for rows.Next() {
 		var profile UserProfile
 		var firstname, lastname, avatar, title, bio sql.NullString
-		var faculty, program, degree, year, uni, mobile sql.NullString
+		var faculty, program, degree, year, uni sql.NullString
 		var linkedin, github, fb sql.NullString
 		var updatedAt sql.NullTime
 
 		err := rows.Scan(
+			&totalRecords,
 			&profile.ID,
 			&profile.Username,
-			&profile.Email,
 			&profile.UserType,
 			&profile.CreatedAt,
 			&firstname,
@@ -970,58 +1092,32 @@ func (m ProfileModel) Search(query string, limit int, offset int) ([]*UserProfil
 			&degree,
 			&year,
 			&uni,
-			&mobile,
 			&linkedin,
 			&github,
 			&fb,
 			&updatedAt,
+			pq.Array(&profile.Skills),
+			&profile.Relevance,
 		)
 		if err != nil {
-			return nil, err
+			return nil, Metadata{}, err
 		}
 
-		// Set nullable fields
-		if firstname.Valid {
-			profile.Firstname = firstname.String
-		}
-		if lastname.Valid {
-			profile.Lastname = lastname.String
-		}
-		if avatar.Valid {
+		profile.Firstname = firstname.String
+		profile.Lastname = lastname.String
+		profile.Title = title.String
+		profile.Bio = bio.String
+		profile.Faculty = faculty.String
+		profile.Program = program.String
+		profile.Degree = degree.String
+		profile.Year = year.String
+		profile.Uni = uni.String
+		profile.LinkedIn = linkedin.String
+		profile.GitHub = github.String
+		profile.FB = fb.String
+		if avatar.Valid && avatar.String != "" && avatar.String != "no key" {
 			profile.Avatar = avatar.String
-		}
-		if title.Valid {
-			profile.Title = title.String
-		}
-		if bio.Valid {
-			profile.Bio = bio.String
-		}
-		if faculty.Valid {
-			profile.Faculty = faculty.String
-		}
-		if program.Valid {
-			profile.Program = program.String
-		}
-		if degree.Valid {
-			profile.Degree = degree.String
-		}
-		if year.Valid {
-			profile.Year = year.String
-		}
-		if uni.Valid {
-			profile.Uni = uni.String
-		}
-		if mobile.Valid {
-			profile.Mobile = mobile.String
-		}
-		if linkedin.Valid {
-			profile.LinkedIn = linkedin.String
-		}
-		if github.Valid {
-			profile.GitHub = github.String
-		}
-		if fb.Valid {
-			profile.FB = fb.String
+			profile.AvatarURL = "/v1/avatars/" + avatar.String
 		}
 		if updatedAt.Valid {
 			profile.UpdatedAt = updatedAt.Time
@@ -1035,43 +1131,31 @@ func (m ProfileModel) Search(query string, limit int, offset int) ([]*UserProfil
 			profile.Title != "" &&
 			profile.Bio != ""
 
-		// Get skills in a separate query
-		skillsQuery := `
-			SELECT skill
-			FROM user_skills
-			WHERE user_id = $1
-		`
-		skillRows, err := m.DB.QueryContext(ctx, skillsQuery, profile.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var skills []string
-		for skillRows.Next() {
-			var skill string
-			err := skillRows.Scan(&skill)
-			if err != nil {
-				skillRows.Close()
-				return nil, err
-			}
-			skills = append(skills, skill)
-		}
-		skillRows.Close()
-		if err = skillRows.Err(); err != nil {
-			return nil, err
-		}
-
-		profile.Skills = skills
 		profiles = append(profiles, &profile)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
 
-	return profiles, nil
+	if filters.Keyset {
+		profiles, metadata := keysetPage(profiles, filters, func(profile *UserProfile) []string {
+			return []string{
+				strconv.FormatFloat(profile.Relevance, 'g', -1, 64),
+				profile.CreatedAt.Format(time.RFC3339Nano),
+				profile.ID.String(),
+			}
+		})
+		return profiles, metadata, nil
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return profiles, metadata, nil
 }
 
+// Delete moves the user's profile to the trash, skills included. The user is
+// treated as having no profile until it is restored.
 func (m ProfileModel) Delete(userID uuid.UUID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -1083,14 +1167,7 @@ func (m ProfileModel) Delete(userID uuid.UUID) error {
 	}
 	defer tx.Rollback()
 
-	// Delete skills first (cascading would handle this, but we'll be explicit)
-	_, err = tx.ExecContext(ctx, "DELETE FROM user_skills WHERE user_id = $1", userID)
-	if err != nil {
-		return err
-	}
-
-	// Delete the profile
-	result, err := tx.ExecContext(ctx, "DELETE FROM user_profiles WHERE user_id = $1", userID)
+	result, err := tx.ExecContext(ctx, "UPDATE user_profiles SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL", userID)
 	if err != nil {
 		return err
 	}
@@ -1104,5 +1181,10 @@ func (m ProfileModel) Delete(userID uuid.UUID) error {
 		return ErrRecordNotFound
 	}
 
+	_, err = tx.ExecContext(ctx, "UPDATE users SET has_profile_created = false, version = version + 1 WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Weights of the signals that make up a recommendation score.
+const (
+	recommendSkillWeight   = 3
+	recommendProgramWeight = 2
+	recommendFacultyWeight = 1
+	recommendLevelWeight   = 1
+	recommendSimilarWeight = 2
+)
+
+// recommendableStatuses are the statuses of ideas a student can still pick up
+// or join.
+var recommendableStatuses = []string{IdeaStatusApproved, IdeaStatusInProgress}
+
+// Recommendation is an idea suggested to a user, with the score it was ranked
+// by and the reasons that contributed to it.
+type Recommendation struct {
+	Idea    *Idea    `json:"idea"`
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// GetRecommended ranks the open ideas the user neither owns nor works on by how
+// well they fit the user: idea tags that match the user's skills, ideas
+// proposed by people in the same program or faculty, a recommended level
+// suited to the user's year of study, and ideas sharing tags with ones the
+// user proposed. Ideas matching none of these are left out.
+func (i IdeaModel) GetRecommended(userID uuid.UUID, filters Filters) ([]*Recommendation, Metadata, error) {
+	query := fmt.Sprintf(`
+        WITH me AS (
+            SELECT lower(COALESCE(p.faculty, '')) AS faculty,
+                   lower(COALESCE(p.program, '')) AS program,
+                   substring(p.year FROM '[0-9]+')::int AS year,
+                   ARRAY(SELECT lower(skill) FROM user_skills WHERE user_id = $1) AS skills
+            FROM users u
+            LEFT JOIN user_profiles p ON p.user_id = u.id AND p.deleted_at IS NULL
+            WHERE u.id = $1
+        ),
+        own AS (
+            SELECT id, title, category, ARRAY(SELECT lower(t) FROM unnest(tags) t) AS tags
+            FROM ideas
+            WHERE user_id = $1 AND deleted_at IS NULL
+        ),
+        candidates AS (
+            SELECT i.id, i.created_at, i.updated_at, i.title, i.description, i.user_id, i.idea_source_id,
+                   COALESCE(i.category, '') AS category, i.tags, i.status, i.learning_outcome, i.recommended_level, i.github_link,
+                   i.website_link, COALESCE(i.pdf, '') AS pdf, i.upvotes, i.downvotes, i.votes, i.version,
+                   ARRAY(SELECT t FROM unnest(i.tags) t WHERE lower(t) = ANY(me.skills)) AS matched_skills,
+                   (me.program <> '' AND lower(COALESCE(ap.program, '')) = me.program) AS same_program,
+                   (me.faculty <> '' AND lower(COALESCE(ap.faculty, '')) = me.faculty) AS same_faculty,
+                   me.year,
+                   COALESCE(me.year <= 1 AND lower(i.recommended_level) = 'beginner'
+                         OR me.year BETWEEN 2 AND 3 AND lower(i.recommended_level) = 'intermediate'
+                         OR me.year >= 4 AND lower(i.recommended_level) = 'advanced', FALSE) AS level_match,
+                   similar.title AS similar_title
+            FROM ideas i
+            CROSS JOIN me
+            LEFT JOIN user_profiles ap ON ap.user_id = i.user_id AND ap.deleted_at IS NULL
+            LEFT JOIN LATERAL (
+                SELECT o.title
+                FROM own o
+                WHERE o.id <> i.id
+                AND cardinality(ARRAY(SELECT unnest(o.tags) INTERSECT SELECT lower(t) FROM unnest(i.tags) t))
+                    >= CASE WHEN o.category = i.category THEN 1 ELSE 2 END
+                ORDER BY cardinality(ARRAY(SELECT unnest(o.tags) INTERSECT SELECT lower(t) FROM unnest(i.tags) t)) DESC,
+                         o.title ASC
+                LIMIT 1
+            ) similar ON TRUE
+            WHERE i.status = ANY($2)
+            AND i.user_id <> $1
+            AND i.deleted_at IS NULL
+            AND NOT EXISTS (SELECT 1 FROM idea_team_members tm
+                            WHERE tm.idea_id = i.id AND tm.user_id = $1 AND tm.status = 'accepted')
+        ),
+        scored AS (
+            SELECT *,
+                   %d * cardinality(matched_skills)
+                   + CASE WHEN same_program THEN %d WHEN same_faculty THEN %d ELSE 0 END
+                   + CASE WHEN level_match THEN %d ELSE 0 END
+                   + CASE WHEN similar_title IS NOT NULL THEN %d ELSE 0 END AS score
+            FROM candidates
+        )
+        SELECT count(*) OVER(), id, created_at, updated_at, title, description, user_id, idea_source_id,
+               category, tags, status, learning_outcome, recommended_level, github_link, website_link, pdf,
+               upvotes, downvotes, votes, version,
+               score, matched_skills, same_program, same_faculty, year, level_match, COALESCE(similar_title, '')
+        FROM scored
+        WHERE score > 0
+        ORDER BY score DESC, votes DESC, created_at DESC, id ASC
+        LIMIT $3 OFFSET $4`,
+		recommendSkillWeight, recommendProgramWeight, recommendFacultyWeight, recommendLevelWeight, recommendSimilarWeight)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, userID, pq.Array(recommendableStatuses), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	recommendations := []*Recommendation{}
+
+	for rows.Next() {
+		var idea Idea
+		var rec Recommendation
+		var matchedSkills []string
+		var sameProgram, sameFaculty, levelMatch bool
+		var year *int
+		var similarTitle string
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&rec.Score,
+			pq.Array(&matchedSkills),
+			&sameProgram,
+			&sameFaculty,
+			&year,
+			&levelMatch,
+			&similarTitle,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		rec.Idea = &idea
+		rec.Reasons = []string{}
+
+		if len(matchedSkills) > 0 {
+			rec.Reasons = append(rec.Reasons, "matches your skills: "+strings.ToLower(strings.Join(matchedSkills, ", ")))
+		}
+		switch {
+		case sameProgram:
+			rec.Reasons = append(rec.Reasons, "proposed by someone in your program")
+		case sameFaculty:
+			rec.Reasons = append(rec.Reasons, "proposed by someone in your faculty")
+		}
+		if levelMatch && year != nil {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("%s level suits year %d students", strings.ToLower(idea.RecommendedLevel), *year))
+		}
+		if similarTitle != "" {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("similar to your idea %q", similarTitle))
+		}
+
+		recommendations = append(recommendations, &rec)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return recommendations, metadata, nil
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// IdeaRevision is an idea's content as it stood at a particular version.
+type IdeaRevision struct {
+	IdeaID           uuid.UUID  `json:"idea_id"`
+	Version          int        `json:"version"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	Category         string     `json:"category"`
+	Tags             []string   `json:"tags"`
+	Status           string     `json:"status"`
+	LearningOutcome  string     `json:"learning_outcome,omitempty"`
+	RecommendedLevel string     `json:"recommended_level,omitempty"`
+	GitHubLink       string     `json:"github_link,omitempty"`
+	WebsiteLink      string     `json:"website_link,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	ReplacedAt       *time.Time `json:"replaced_at,omitempty"`
+	Current          bool       `json:"current"`
+}
+
+// FieldChange describes how a single field differs between two revisions.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffRevisions returns the fields whose values differ between from and to.
+func DiffRevisions(from, to *IdeaRevision) []FieldChange {
+	changes := []FieldChange{}
+
+	addString := func(field, a, b string) {
+		if a != b {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	addString("title", from.Title, to.Title)
+	addString("description", from.Description, to.Description)
+	addString("category", from.Category, to.Category)
+
+	if !slices.Equal(from.Tags, to.Tags) {
+		changes = append(changes, FieldChange{Field: "tags", From: from.Tags, To: to.Tags})
+	}
+
+	addString("status", from.Status, to.Status)
+	addString("learning_outcome", from.LearningOutcome, to.LearningOutcome)
+	addString("recommended_level", from.RecommendedLevel, to.RecommendedLevel)
+	addString("github_link", from.GitHubLink, to.GitHubLink)
+	addString("website_link", from.WebsiteLink, to.WebsiteLink)
+
+	return changes
+}
+
+// snapshotIdea copies the idea row at the given version into idea_revisions.
+// It locks the row for the rest of the transaction and returns ErrEditConflict
+// if the idea is no longer at that version.
+func snapshotIdea(ctx context.Context, tx *sql.Tx, ideaID uuid.UUID, version int) error {
+	query := `INSERT INTO idea_revisions (idea_id, version, title, description, category, tags, status,
+	              learning_outcome, recommended_level, github_link, website_link, updated_at)
+	          SELECT id, version, title, description, COALESCE(category, ''), tags, status,
+	              learning_outcome, recommended_level, github_link, website_link, updated_at
+	          FROM ideas
+	          WHERE id = $1 AND version = $2
+	          FOR UPDATE`
+
+	result, err := tx.ExecContext(ctx, query, ideaID, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForIdea returns the stored revisions of an idea, newest first. The
+// current version lives in the ideas table and is not included.
+func (m RevisionModel) GetAllForIdea(ideaID uuid.UUID) ([]*IdeaRevision, error) {
+	query := `SELECT idea_id, version, title, description, category, tags, status,
+                    COALESCE(learning_outcome, ''), COALESCE(recommended_level, ''),
+                    COALESCE(github_link, ''), COALESCE(website_link, ''), updated_at, replaced_at
+             FROM idea_revisions
+             WHERE idea_id = $1
+             ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*IdeaRevision{}
+
+	for rows.Next() {
+		var revision IdeaRevision
+
+		err := rows.Scan(
+			&revision.IdeaID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Description,
+			&revision.Category,
+			pq.Array(&revision.Tags),
+			&revision.Status,
+			&revision.LearningOutcome,
+			&revision.RecommendedLevel,
+			&revision.GitHubLink,
+			&revision.WebsiteLink,
+			&revision.UpdatedAt,
+			&revision.ReplacedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Get returns the idea as it was at the given version. Asking for the idea's
+// current version returns the live row marked as Current.
+func (m RevisionModel) Get(idea *Idea, version int) (*IdeaRevision, error) {
+	if version == idea.Version {
+		return CurrentRevision(idea), nil
+	}
+
+	query := `SELECT idea_id, version, title, description, category, tags, status,
+                    COALESCE(learning_outcome, ''), COALESCE(recommended_level, ''),
+                    COALESCE(github_link, ''), COALESCE(website_link, ''), updated_at, replaced_at
+             FROM idea_revisions
+             WHERE idea_id = $1 AND version = $2`
+
+	var revision IdeaRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, idea.ID, version).Scan(
+		&revision.IdeaID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Description,
+		&revision.Category,
+		pq.Array(&revision.Tags),
+		&revision.Status,
+		&revision.LearningOutcome,
+		&revision.RecommendedLevel,
+		&revision.GitHubLink,
+		&revision.WebsiteLink,
+		&revision.UpdatedAt,
+		&revision.ReplacedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// CurrentRevision presents the live state of an idea as a revision.
+func CurrentRevision(idea *Idea) *IdeaRevision {
+	return &IdeaRevision{
+		IdeaID:           idea.ID,
+		Version:          idea.Version,
+		Title:            idea.Title,
+		Description:      idea.Description,
+		Category:         idea.Category,
+		Tags:             idea.Tags,
+		Status:           idea.Status,
+		LearningOutcome:  idea.LearningOutcome,
+		RecommendedLevel: idea.RecommendedLevel,
+		GitHubLink:       idea.GitHubLink,
+		WebsiteLink:      idea.WebsiteLink,
+		UpdatedAt:        idea.UpdatedAt,
+		Current:          true,
+	}
+}
+
+// Restore copies the content of a revision back onto the idea. Status is
+// left alone because it is governed by the status workflow. The caller saves
+// the idea with IdeaModel.Update, which records the overwritten version.
+func (r *IdeaRevision) Restore(idea *Idea) {
+	idea.Title = r.Title
+	idea.Description = r.Description
+	idea.Category = r.Category
+	idea.Tags = r.Tags
+	idea.LearningOutcome = r.LearningOutcome
+	idea.RecommendedLevel = r.RecommendedLevel
+	idea.GitHubLink = r.GitHubLink
+	idea.WebsiteLink = r.WebsiteLink
+}
@@ -0,0 +1,334 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// exchanged is presented again. The whole session is revoked when it happens,
+// as either the user or an attacker holds a stolen copy.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// Session describes a login: the family of access and refresh tokens issued
+// to one device, identified by the family ID. Current marks the session the
+// request was made with.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Expiry     time.Time  `json:"expiry"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
+}
+
+// SessionTokens are the tokens handed to a client at login and on refresh.
+// Access is nil when the caller issues signed access tokens itself.
+type SessionTokens struct {
+	SessionID uuid.UUID
+	Access    *Token
+	Refresh   *Token
+}
+
+// NewSession starts a session for the user, issuing a short-lived access
+// token and a refresh token that records the device it was issued to. An
+// accessTTL of zero skips the access token.
+func (m TokenModel) NewSession(userID uuid.UUID, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*SessionTokens, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tokens, err := insertSessionTokens(ctx, tx, userID, uuid.New(), accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, tx.Commit()
+}
+
+// RotateRefreshToken exchanges a refresh token for a new access and refresh
+// token in the same session. The old refresh token is marked used rather than
+// deleted, so that presenting it again revokes the session and returns
+// ErrRefreshTokenReused along with the ID of the revoked session.
+func (m TokenModel) RotateRefreshToken(tokenPlaintext string, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*SessionTokens, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT t.user_id, t.family_id, t.used_at
+	          FROM tokens t
+	          INNER JOIN users u ON u.id = t.user_id
+	          WHERE t.hash = $1 AND t.scope = $2 AND t.expiry > NOW() AND u.deleted_at IS NULL
+	          FOR UPDATE OF t`
+
+	var (
+		userID   uuid.UUID
+		familyID uuid.UUID
+		usedAt   *time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query, tokenHash[:], ScopeRefresh).Scan(&userID, &familyID, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if usedAt != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family_id = $1`, familyID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		return &SessionTokens{SessionID: familyID}, ErrRefreshTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE hash = $1`, tokenHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// The access tokens issued before are replaced by the new one
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family_id = $1 AND scope = $2`, familyID, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := insertSessionTokens(ctx, tx, userID, familyID, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, tx.Commit()
+}
+
+func insertSessionTokens(ctx context.Context, tx *sql.Tx, userID, familyID uuid.UUID, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*SessionTokens, error) {
+	tokens := &SessionTokens{SessionID: familyID}
+
+	refresh, err := generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	tokens.Refresh = refresh
+
+	issued := []*Token{refresh}
+
+	if accessTTL > 0 {
+		access, err := generateToken(userID, accessTTL, ScopeAuthentication)
+		if err != nil {
+			return nil, err
+		}
+		tokens.Access = access
+
+		issued = append(issued, access)
+	}
+
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope, family_id, user_agent, ip) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	for _, token := range issued {
+		token.FamilyID = familyID
+		token.UserAgent = userAgent
+		token.IP = ip
+
+		_, err = tx.ExecContext(ctx, query, token.Hash, token.UserID, token.Expiry, token.Scope, token.FamilyID, token.UserAgent, token.IP)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+// TouchSession records that the authentication token was just used and
+// returns the ID of its session. The time is only written when the previous
+// one is more than a minute old, so that most requests stay read-only.
+func (m TokenModel) TouchSession(tokenPlaintext string) (uuid.UUID, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `WITH touched AS (
+	              UPDATE tokens
+	              SET last_used_at = NOW()
+	              WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+	              AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	          )
+	          SELECT family_id FROM tokens
+	          WHERE hash = $1 AND scope = $2 AND expiry > NOW()`
+
+	var id uuid.UUID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], ScopeAuthentication).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return uuid.Nil, ErrRecordNotFound
+		default:
+			return uuid.Nil, err
+		}
+	}
+
+	return id, nil
+}
+
+// GetSessionsForUser returns the user's sessions that can still be used or
+// refreshed, most recently used first. A session reports the device and
+// address of its latest token.
+func (m TokenModel) GetSessionsForUser(userID uuid.UUID) ([]*Session, error) {
+	query := `SELECT family_id, min(created_at), max(last_used_at), max(expiry),
+	                 (array_agg(user_agent ORDER BY created_at DESC))[1],
+	                 (array_agg(ip ORDER BY created_at DESC))[1]
+	          FROM tokens
+	          WHERE user_id = $1 AND scope IN ($2, $3) AND expiry > NOW() AND used_at IS NULL
+	          GROUP BY family_id
+	          ORDER BY COALESCE(max(last_used_at), min(created_at)) DESC, family_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ScopeAuthentication, ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.UserAgent,
+			&session.IP,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession revokes all access and refresh tokens of one of the user's
+// sessions
+func (m TokenModel) DeleteSession(id, userID uuid.UUID) error {
+	query := `DELETE FROM tokens WHERE family_id = $1 AND user_id = $2 AND scope IN ($3, $4)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, ScopeAuthentication, ScopeRefresh)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DenySessions records that the signed access tokens of the sessions must be
+// rejected until the given time
+func (m TokenModel) DenySessions(ids []uuid.UUID, until time.Time) error {
+	sessionIDs := make([]string, len(ids))
+	for i, id := range ids {
+		sessionIDs[i] = id.String()
+	}
+
+	query := `INSERT INTO session_denylist (session_id, expiry)
+	          SELECT unnest($1::uuid[]), $2
+	          ON CONFLICT (session_id) DO UPDATE SET expiry = GREATEST(session_denylist.expiry, EXCLUDED.expiry)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(sessionIDs), until)
+
+	return err
+}
+
+// GetDeniedSessions returns the sessions whose signed access tokens are
+// currently rejected, with the time until which they are, and forgets those
+// that no longer need to be.
+func (m TokenModel) GetDeniedSessions() (map[uuid.UUID]time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM session_denylist WHERE expiry <= NOW()`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT session_id, expiry FROM session_denylist`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	denied := make(map[uuid.UUID]time.Time)
+
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			expiry time.Time
+		)
+
+		err := rows.Scan(&id, &expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		denied[id] = expiry
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return denied, nil
+}
@@ -0,0 +1,396 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// Task statuses are the columns of an idea's task board, in board order.
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusReview     = "review"
+	TaskStatusDone       = "done"
+)
+
+var TaskStatuses = []string{TaskStatusTodo, TaskStatusInProgress, TaskStatusReview, TaskStatusDone}
+
+// TaskPositionEnd places a task at the bottom of its column.
+const TaskPositionEnd = math.MaxInt32
+
+// Task is a card on an idea's task board. Position orders the tasks within a
+// status column, starting at 0.
+type Task struct {
+	ID           uuid.UUID  `json:"id"`
+	IdeaID       uuid.UUID  `json:"idea_id"`
+	MilestoneID  *uuid.UUID `json:"milestone_id,omitempty"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description,omitempty"`
+	AssigneeID   *uuid.UUID `json:"assignee_id,omitempty"`
+	AssigneeName string     `json:"assignee_name,omitempty"`
+	Status       string     `json:"status"`
+	Position     int        `json:"position"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Version      int        `json:"version"`
+}
+
+// TaskFilter narrows down the tasks shown on a board. Nil fields do not
+// filter.
+type TaskFilter struct {
+	AssigneeID  *uuid.UUID
+	MilestoneID *uuid.UUID
+}
+
+// BoardColumn holds the tasks in one status, in board order.
+type BoardColumn struct {
+	Status string  `json:"status"`
+	Tasks  []*Task `json:"tasks"`
+}
+
+// IdeaProgress sums up the work tracked on an idea. Percent is the share of
+// tasks that are done or, while the idea has no tasks, of milestones that are
+// completed.
+type IdeaProgress struct {
+	Percent             int `json:"percent"`
+	TasksTotal          int `json:"tasks_total"`
+	TasksDone           int `json:"tasks_done"`
+	MilestonesTotal     int `json:"milestones_total"`
+	MilestonesCompleted int `json:"milestones_completed"`
+}
+
+func ValidateTask(v *validator.Validator, task *Task) {
+	v.Check(task.Title != "", "title", "must be provided")
+	v.Check(len(task.Title) <= 255, "title", "must not be more than 255 bytes long")
+	v.Check(len(task.Description) <= 5000, "description", "must not be more than 5000 bytes long")
+	v.Check(validator.PermittedValue(task.Status, TaskStatuses...), "status", "must be one of todo, in_progress, review or done")
+	v.Check(task.Position >= 0, "position", "must not be negative")
+}
+
+type TaskModel struct {
+	DB *sql.DB
+}
+
+// lockBoard locks the idea so that concurrent changes to its board cannot
+// leave two tasks in the same place.
+func lockBoard(ctx context.Context, tx *sql.Tx, ideaID uuid.UUID) error {
+	var id uuid.UUID
+
+	err := tx.QueryRowContext(ctx, `SELECT id FROM ideas WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, ideaID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert adds the task at the bottom of its column.
+func (m TaskModel) Insert(task *Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockBoard(ctx, tx, task.IdeaID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO idea_tasks (idea_id, milestone_id, title, description, assignee_id, status, position, created_by)
+	          SELECT $1, $2, $3, $4, $5, $6, count(*), $7
+	          FROM idea_tasks
+	          WHERE idea_id = $1 AND status = $6
+	          RETURNING id, position, created_at, updated_at, version`
+
+	args := []any{task.IdeaID, task.MilestoneID, task.Title, task.Description, task.AssigneeID, task.Status, task.CreatedBy}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&task.ID, &task.Position, &task.CreatedAt, &task.UpdatedAt, &task.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m TaskModel) Get(id uuid.UUID) (*Task, error) {
+	query := `SELECT t.id, t.idea_id, t.milestone_id, t.title, t.description, t.assignee_id,
+	                 COALESCE(u.user_name, ''), t.status, t.position, t.created_by,
+	                 t.created_at, t.updated_at, t.version
+	          FROM idea_tasks t
+	          LEFT JOIN users u ON u.id = t.assignee_id
+	          WHERE t.id = $1`
+
+	var task Task
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&task.ID,
+		&task.IdeaID,
+		&task.MilestoneID,
+		&task.Title,
+		&task.Description,
+		&task.AssigneeID,
+		&task.AssigneeName,
+		&task.Status,
+		&task.Position,
+		&task.CreatedBy,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &task, nil
+}
+
+// Update saves the task's fields and moves it to its Status column at
+// Position, shifting the tasks around its old and new places. Positions past
+// the end of the column, such as TaskPositionEnd, put it at the bottom; the
+// position it ends up at is written back to the task.
+func (m TaskModel) Update(task *Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockBoard(ctx, tx, task.IdeaID)
+	if err != nil {
+		return err
+	}
+
+	var oldStatus string
+	var oldPosition int
+
+	query := `SELECT status, position FROM idea_tasks WHERE id = $1 AND version = $2`
+
+	err = tx.QueryRowContext(ctx, query, task.ID, task.Version).Scan(&oldStatus, &oldPosition)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if oldStatus != task.Status || oldPosition != task.Position {
+		// Close the gap the task leaves behind
+		query = `UPDATE idea_tasks SET position = position - 1
+		         WHERE idea_id = $1 AND status = $2 AND position > $3`
+
+		_, err = tx.ExecContext(ctx, query, task.IdeaID, oldStatus, oldPosition)
+		if err != nil {
+			return err
+		}
+
+		var columnSize int
+
+		query = `SELECT count(*) FROM idea_tasks WHERE idea_id = $1 AND status = $2 AND id <> $3`
+
+		err = tx.QueryRowContext(ctx, query, task.IdeaID, task.Status, task.ID).Scan(&columnSize)
+		if err != nil {
+			return err
+		}
+
+		task.Position = min(task.Position, columnSize)
+
+		// Make room at the new place
+		query = `UPDATE idea_tasks SET position = position + 1
+		         WHERE idea_id = $1 AND status = $2 AND position >= $3 AND id <> $4`
+
+		_, err = tx.ExecContext(ctx, query, task.IdeaID, task.Status, task.Position, task.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE idea_tasks
+	         SET milestone_id = $1, title = $2, description = $3, assignee_id = $4, status = $5, position = $6,
+	             updated_at = NOW(), version = version + 1
+	         WHERE id = $7
+	         RETURNING updated_at, version`
+
+	args := []any{
+		task.MilestoneID,
+		task.Title,
+		task.Description,
+		task.AssigneeID,
+		task.Status,
+		task.Position,
+		task.ID,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&task.UpdatedAt, &task.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a task and closes the gap it leaves in its column.
+func (m TaskModel) Delete(task *Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockBoard(ctx, tx, task.IdeaID)
+	if err != nil {
+		return err
+	}
+
+	var status string
+	var position int
+
+	query := `DELETE FROM idea_tasks WHERE id = $1 RETURNING status, position`
+
+	err = tx.QueryRowContext(ctx, query, task.ID).Scan(&status, &position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `UPDATE idea_tasks SET position = position - 1
+	         WHERE idea_id = $1 AND status = $2 AND position > $3`
+
+	_, err = tx.ExecContext(ctx, query, task.IdeaID, status, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetBoard returns the idea's tasks matching the filter as a board, with a
+// column for every status even when it is empty.
+func (m TaskModel) GetBoard(ideaID uuid.UUID, filter TaskFilter) ([]*BoardColumn, error) {
+	query := `SELECT t.id, t.idea_id, t.milestone_id, t.title, t.description, t.assignee_id,
+	                 COALESCE(u.user_name, ''), t.status, t.position, t.created_by,
+	                 t.created_at, t.updated_at, t.version
+	          FROM idea_tasks t
+	          LEFT JOIN users u ON u.id = t.assignee_id
+	          WHERE t.idea_id = $1
+	          AND ($2::uuid IS NULL OR t.assignee_id = $2)
+	          AND ($3::uuid IS NULL OR t.milestone_id = $3)
+	          ORDER BY t.position ASC, t.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID, filter.AssigneeID, filter.MilestoneID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	board := make([]*BoardColumn, len(TaskStatuses))
+	columns := make(map[string]*BoardColumn, len(TaskStatuses))
+
+	for i, status := range TaskStatuses {
+		board[i] = &BoardColumn{Status: status, Tasks: []*Task{}}
+		columns[status] = board[i]
+	}
+
+	for rows.Next() {
+		var task Task
+
+		err := rows.Scan(
+			&task.ID,
+			&task.IdeaID,
+			&task.MilestoneID,
+			&task.Title,
+			&task.Description,
+			&task.AssigneeID,
+			&task.AssigneeName,
+			&task.Status,
+			&task.Position,
+			&task.CreatedBy,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+			&task.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if column, ok := columns[task.Status]; ok {
+			column.Tasks = append(column.Tasks, &task)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// GetProgress sums up the tasks and milestones of the idea.
+func (m TaskModel) GetProgress(ideaID uuid.UUID) (*IdeaProgress, error) {
+	query := `SELECT (SELECT count(*) FROM idea_tasks WHERE idea_id = $1),
+	                 (SELECT count(*) FROM idea_tasks WHERE idea_id = $1 AND status = $2),
+	                 (SELECT count(*) FROM idea_milestones WHERE idea_id = $1),
+	                 (SELECT count(*) FROM idea_milestones WHERE idea_id = $1 AND status = $3)`
+
+	var progress IdeaProgress
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, ideaID, TaskStatusDone, MilestoneStatusCompleted).Scan(
+		&progress.TasksTotal,
+		&progress.TasksDone,
+		&progress.MilestonesTotal,
+		&progress.MilestonesCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case progress.TasksTotal > 0:
+		progress.Percent = progress.TasksDone * 100 / progress.TasksTotal
+	case progress.MilestonesTotal > 0:
+		progress.Percent = progress.MilestonesCompleted * 100 / progress.MilestonesTotal
+	}
+
+	return &progress, nil
+}
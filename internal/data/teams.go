@@ -0,0 +1,372 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	TeamRoleLead   = "lead"
+	TeamRoleMember = "member"
+	TeamRoleMentor = "mentor"
+
+	// TeamRoleOwner is reported for the idea's owner, who is not stored in
+	// idea_team_members but always belongs to the team.
+	TeamRoleOwner = "owner"
+)
+
+const (
+	TeamStatusInvited  = "invited"
+	TeamStatusAccepted = "accepted"
+	TeamStatusDeclined = "declined"
+)
+
+var (
+	ErrTeamFull            = errors.New("team is full")
+	ErrDuplicateTeamMember = errors.New("duplicate team member")
+)
+
+var TeamRoles = []string{TeamRoleLead, TeamRoleMember, TeamRoleMentor}
+
+type TeamMember struct {
+	IdeaID      uuid.UUID  `json:"idea_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	UserName    string     `json:"user_name"`
+	Role        string     `json:"role"`
+	Status      string     `json:"status"`
+	InvitedBy   *uuid.UUID `json:"invited_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	Version     int        `json:"version"`
+}
+
+// UserTeam is an idea a user works on, either as its owner or as an accepted
+// team member.
+type UserTeam struct {
+	IdeaID     uuid.UUID `json:"idea_id"`
+	IdeaTitle  string    `json:"idea_title"`
+	IdeaStatus string    `json:"idea_status"`
+	Role       string    `json:"role"`
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+func ValidateTeamMember(v *validator.Validator, member *TeamMember) {
+	v.Check(member.UserID != uuid.Nil, "user_id", "must be provided")
+	v.Check(validator.PermittedValue(member.Role, TeamRoles...), "role", "must be one of lead, member or mentor")
+}
+
+type TeamModel struct {
+	DB *sql.DB
+}
+
+// Invite adds an invitation for the member to join the idea's team. The owner
+// and every invited or accepted member count towards maxSize. A user who
+// previously declined may be invited again.
+func (m TeamModel) Invite(member *TeamMember, maxSize int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the idea so concurrent invitations cannot overshoot the cap
+	var size int
+	query := `SELECT 1 + (SELECT count(*) FROM idea_team_members
+	                      WHERE idea_id = ideas.id AND status IN ('invited', 'accepted'))
+	          FROM ideas
+	          WHERE id = $1 AND deleted_at IS NULL
+	          FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, member.IdeaID).Scan(&size)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if size >= maxSize {
+		return ErrTeamFull
+	}
+
+	query = `INSERT INTO idea_team_members (idea_id, user_id, role, invited_by)
+	         VALUES ($1, $2, $3, $4)
+	         ON CONFLICT (idea_id, user_id) DO UPDATE
+	         SET role = EXCLUDED.role, status = 'invited', invited_by = EXCLUDED.invited_by,
+	             created_at = NOW(), responded_at = NULL, version = idea_team_members.version + 1
+	         WHERE idea_team_members.status = 'declined'
+	         RETURNING status, created_at, version`
+
+	args := []any{member.IdeaID, member.UserID, member.Role, member.InvitedBy}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&member.Status, &member.CreatedAt, &member.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateTeamMember
+		case err.Error() == `pq: insert or update on table "idea_team_members" violates foreign key constraint "idea_team_members_user_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	member.RespondedAt = nil
+
+	return tx.Commit()
+}
+
+func (m TeamModel) Get(ideaID, userID uuid.UUID) (*TeamMember, error) {
+	query := `SELECT t.idea_id, t.user_id, u.user_name, t.role, t.status, t.invited_by,
+	                 t.created_at, t.responded_at, t.version
+	          FROM idea_team_members t
+	          INNER JOIN users u ON u.id = t.user_id
+	          WHERE t.idea_id = $1 AND t.user_id = $2`
+
+	var member TeamMember
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, ideaID, userID).Scan(
+		&member.IdeaID,
+		&member.UserID,
+		&member.UserName,
+		&member.Role,
+		&member.Status,
+		&member.InvitedBy,
+		&member.CreatedAt,
+		&member.RespondedAt,
+		&member.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &member, nil
+}
+
+// Update saves the member's role and status, using the version for
+// optimistic locking.
+func (m TeamModel) Update(member *TeamMember) error {
+	query := `UPDATE idea_team_members
+	          SET role = $1, status = $2, responded_at = $3, version = version + 1
+	          WHERE idea_id = $4 AND user_id = $5 AND version = $6
+	          RETURNING version`
+
+	args := []any{member.Role, member.Status, member.RespondedAt, member.IdeaID, member.UserID, member.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&member.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m TeamModel) Delete(ideaID, userID uuid.UUID) error {
+	query := `DELETE FROM idea_team_members
+	          WHERE idea_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, ideaID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForIdea returns the idea's team members and outstanding invitations.
+// When statuses is non-empty only members in one of those statuses are returned.
+func (m TeamModel) GetAllForIdea(ideaID uuid.UUID, statuses []string) ([]*TeamMember, error) {
+	query := `SELECT t.idea_id, t.user_id, u.user_name, t.role, t.status, t.invited_by,
+	                 t.created_at, t.responded_at, t.version
+	          FROM idea_team_members t
+	          INNER JOIN users u ON u.id = t.user_id
+	          WHERE t.idea_id = $1
+	          AND (t.status = ANY($2) OR cardinality($2::text[]) = 0)
+	          ORDER BY t.created_at ASC, t.user_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ideaID, pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*TeamMember{}
+
+	for rows.Next() {
+		var member TeamMember
+
+		err := rows.Scan(
+			&member.IdeaID,
+			&member.UserID,
+			&member.UserName,
+			&member.Role,
+			&member.Status,
+			&member.InvitedBy,
+			&member.CreatedAt,
+			&member.RespondedAt,
+			&member.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetInvitationsForUser returns the invitations a user has not yet answered.
+func (m TeamModel) GetInvitationsForUser(userID uuid.UUID) ([]*TeamMember, error) {
+	query := `SELECT t.idea_id, t.user_id, u.user_name, t.role, t.status, t.invited_by,
+	                 t.created_at, t.responded_at, t.version
+	          FROM idea_team_members t
+	          INNER JOIN users u ON u.id = t.user_id
+	          INNER JOIN ideas i ON i.id = t.idea_id AND i.deleted_at IS NULL
+	          WHERE t.user_id = $1 AND t.status = 'invited'
+	          ORDER BY t.created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*TeamMember{}
+
+	for rows.Next() {
+		var member TeamMember
+
+		err := rows.Scan(
+			&member.IdeaID,
+			&member.UserID,
+			&member.UserName,
+			&member.Role,
+			&member.Status,
+			&member.InvitedBy,
+			&member.CreatedAt,
+			&member.RespondedAt,
+			&member.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, &member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// GetTeamsForUser returns the ideas a user owns or has joined, newest first.
+// When statuses is non-empty only ideas in one of those statuses are returned.
+func (m TeamModel) GetTeamsForUser(userID uuid.UUID, statuses []string) ([]*UserTeam, error) {
+	query := `SELECT id, title, status, 'owner', created_at
+	          FROM ideas
+	          WHERE user_id = $1 AND deleted_at IS NULL
+	          AND (status = ANY($2) OR cardinality($2::text[]) = 0)
+	          UNION ALL
+	          SELECT i.id, i.title, i.status, t.role, COALESCE(t.responded_at, t.created_at)
+	          FROM idea_team_members t
+	          INNER JOIN ideas i ON i.id = t.idea_id AND i.deleted_at IS NULL
+	          WHERE t.user_id = $1 AND t.status = 'accepted'
+	          AND (i.status = ANY($2) OR cardinality($2::text[]) = 0)
+	          ORDER BY 5 DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []*UserTeam{}
+
+	for rows.Next() {
+		var team UserTeam
+
+		err := rows.Scan(&team.IdeaID, &team.IdeaTitle, &team.IdeaStatus, &team.Role, &team.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		teams = append(teams, &team)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+// IsMember reports whether the user has accepted a place on the idea's team.
+func (m TeamModel) IsMember(ideaID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM idea_team_members
+	              WHERE idea_id = $1 AND user_id = $2 AND status = 'accepted'
+	          )`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, ideaID, userID).Scan(&exists)
+
+	return exists, err
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
 	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
@@ -15,7 +16,11 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeOAuthLogin     = "oauth-login"
 	ScopePasswordReset  = "password-reset"
+	ScopeRefresh        = "refresh"
+	ScopeTwoFactor      = "two-factor"
+	ScopeUnlock         = "unlock"
 )
 
 type Token struct {
@@ -24,13 +29,17 @@ type Token struct {
 	UserID    uuid.UUID `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	FamilyID  uuid.UUID `json:"-"`
+	UserAgent string    `json:"-"`
+	IP        string    `json:"-"`
 }
 
 func generateToken(userID uuid.UUID, ttl time.Duration, scope string) (*Token, error) {
 	token := &Token{
-		UserID: userID,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		UserID:   userID,
+		Expiry:   time.Now().Add(ttl),
+		Scope:    scope,
+		FamilyID: uuid.New(),
 	}
 
 	randomBytes := make([]byte, 16)
@@ -71,9 +80,9 @@ func (m TokenModel) New(userID uuid.UUID, ttl time.Duration, scope string) (*Tok
 }
 
 func (m TokenModel) Insert(token *Token) error {
-	query := `INSERT INTO tokens (hash, user_id, expiry, scope) VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope, family_id, user_agent, ip) VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
-	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, token.FamilyID, token.UserAgent, token.IP}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 
@@ -93,3 +102,31 @@ func (m TokenModel) DeleteAllForUser(scope string, userID uuid.UUID) error {
 
 	return err
 }
+
+// Consume deletes the unexpired token with the plaintext and scope and returns
+// the ID of the user it was issued to. The lookup and the delete are a single
+// statement, so of two concurrent requests with the same one-time token only
+// one gets the user back.
+func (m TokenModel) Consume(scope, tokenPlaintext string) (uuid.UUID, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `DELETE FROM tokens WHERE hash = $1 AND scope = $2 AND expiry > NOW() RETURNING user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	var userID uuid.UUID
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], scope).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return uuid.Nil, ErrRecordNotFound
+		default:
+			return uuid.Nil, err
+		}
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,399 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// TrashedIdea is a deleted idea that can be restored until PurgeAt.
+type TrashedIdea struct {
+	Idea    *Idea     `json:"idea"`
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashedProfile is a deleted profile that can be restored until PurgeAt.
+type TrashedProfile struct {
+	Profile *Profile  `json:"profile"`
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// GetTrash lists the ideas the user deleted within the retention window, most
+// recently deleted first.
+func (i IdeaModel) GetTrash(userID uuid.UUID, retention time.Duration, filters Filters) ([]*TrashedIdea, Metadata, error) {
+	query := `SELECT count(*) OVER(), id, created_at, updated_at, title, description, user_id, idea_source_id,
+                    COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+                    website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version, publish_at, deleted_at
+             FROM ideas
+             WHERE user_id = $1 AND deleted_at > $2
+             ORDER BY deleted_at DESC, id ASC
+             LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, userID, time.Now().Add(-retention), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	trash := []*TrashedIdea{}
+
+	for rows.Next() {
+		var idea Idea
+
+		err := rows.Scan(
+			&totalRecords,
+			&idea.ID,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+			&idea.Title,
+			&idea.Description,
+			&idea.UserID,
+			&idea.IdeaSourceID,
+			&idea.Category,
+			pq.Array(&idea.Tags),
+			&idea.Status,
+			&idea.LearningOutcome,
+			&idea.RecommendedLevel,
+			&idea.GitHubLink,
+			&idea.WebsiteLink,
+			&idea.Pdf,
+			&idea.Upvotes,
+			&idea.Downvotes,
+			&idea.Votes,
+			&idea.Version,
+			&idea.PublishAt,
+			&idea.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		trash = append(trash, &TrashedIdea{Idea: &idea, PurgeAt: idea.DeletedAt.Add(retention)})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return trash, metadata, nil
+}
+
+// GetDeleted returns an idea in the trash that is still within the retention
+// window. Ideas deleted along with their owner's account are left out: they
+// come back when the account is restored.
+func (i IdeaModel) GetDeleted(id uuid.UUID, retention time.Duration) (*Idea, error) {
+	query := `SELECT id, created_at, updated_at, title, description, user_id, idea_source_id,
+                    COALESCE(category, ''), tags, status, learning_outcome, recommended_level, github_link,
+                    website_link, COALESCE(pdf, ''), upvotes, downvotes, votes, version, publish_at, deleted_at
+             FROM ideas
+             WHERE id = $1 AND deleted_at > $2
+             AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)`
+
+	var idea Idea
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := i.DB.QueryRowContext(ctx, query, id, time.Now().Add(-retention)).Scan(
+		&idea.ID,
+		&idea.CreatedAt,
+		&idea.UpdatedAt,
+		&idea.Title,
+		&idea.Description,
+		&idea.UserID,
+		&idea.IdeaSourceID,
+		&idea.Category,
+		pq.Array(&idea.Tags),
+		&idea.Status,
+		&idea.LearningOutcome,
+		&idea.RecommendedLevel,
+		&idea.GitHubLink,
+		&idea.WebsiteLink,
+		&idea.Pdf,
+		&idea.Upvotes,
+		&idea.Downvotes,
+		&idea.Votes,
+		&idea.Version,
+		&idea.PublishAt,
+		&idea.DeletedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &idea, nil
+}
+
+// Restore takes an idea out of the trash. It returns ErrRecordNotFound if the
+// idea was restored or purged in the meantime.
+func (i IdeaModel) Restore(idea *Idea) error {
+	query := `UPDATE ideas SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := i.DB.ExecContext(ctx, query, idea.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	idea.DeletedAt = nil
+
+	return nil
+}
+
+// PurgeDeleted permanently removes the ideas deleted before the given time and
+// returns the keys of the PDFs no remaining idea refers to, so their files can
+// be removed too. Forks share their source's PDF, which is why a key can
+// outlive the idea it was uploaded for.
+func (i IdeaModel) PurgeDeleted(before time.Time) ([]string, error) {
+	query := `
+        WITH purged AS (
+            DELETE FROM ideas
+            WHERE deleted_at < $1
+            RETURNING id, COALESCE(pdf, '') AS pdf
+        )
+        SELECT DISTINCT p.pdf
+        FROM purged p
+        WHERE p.pdf NOT IN ('', 'no key')
+        AND NOT EXISTS (SELECT 1 FROM ideas o
+                        WHERE o.pdf = p.pdf AND o.id NOT IN (SELECT id FROM purged))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	return queryStrings(ctx, i.DB, query, before)
+}
+
+// GetDeleted returns the user's profile if it is in the trash and still within
+// the retention window.
+func (m ProfileModel) GetDeleted(userID uuid.UUID, retention time.Duration) (*Profile, error) {
+	query := `
+		SELECT user_id, firstname, lastname, avatar, title, bio, faculty,
+		       program, degree, year, uni, mobile, linkedin, github, fb,
+		       created_at, updated_at, deleted_at
+		FROM user_profiles
+		WHERE user_id = $1 AND deleted_at > $2`
+
+	var profile Profile
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, time.Now().Add(-retention)).Scan(
+		&profile.UserID,
+		&profile.Firstname,
+		&profile.Lastname,
+		&profile.Avatar,
+		&profile.Title,
+		&profile.Bio,
+		&profile.Faculty,
+		&profile.Program,
+		&profile.Degree,
+		&profile.Year,
+		&profile.Uni,
+		&profile.Mobile,
+		&profile.LinkedIn,
+		&profile.GitHub,
+		&profile.FB,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&profile.DeletedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	profile.Skills, err = m.GetSkills(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// Restore takes the user's profile out of the trash if it was deleted within
+// the retention window.
+func (m ProfileModel) Restore(userID uuid.UUID, retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE user_profiles SET deleted_at = NULL
+	          WHERE user_id = $1 AND deleted_at > $2`
+
+	result, err := tx.ExecContext(ctx, query, userID, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET has_profile_created = true, version = version + 1 WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeleted permanently removes the profiles deleted before the given
+// time, with their skills, and returns the keys of their avatars.
+func (m ProfileModel) PurgeDeleted(before time.Time) ([]string, error) {
+	query := `
+        WITH purged AS (
+            DELETE FROM user_profiles
+            WHERE deleted_at < $1
+            RETURNING user_id, COALESCE(avatar, '') AS avatar
+        ), skills AS (
+            DELETE FROM user_skills
+            WHERE user_id IN (SELECT user_id FROM purged)
+        )
+        SELECT avatar
+        FROM purged
+        WHERE avatar NOT IN ('', 'no key')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	return queryStrings(ctx, m.DB, query, before)
+}
+
+// Restore brings back a user account deleted within the retention window,
+// along with the profile and ideas that were deleted with it. Items the user
+// had deleted beforehand stay in the trash.
+func (m UserModal) Restore(id uuid.UUID, retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+
+	query := `SELECT deleted_at FROM users
+	          WHERE id = $1 AND deleted_at > $2
+	          FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, id, time.Now().Add(-retention)).Scan(&deletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	// The email address may have been taken by a new account in the meantime
+	_, err = tx.ExecContext(ctx, "UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = $1", id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE user_profiles SET deleted_at = NULL WHERE user_id = $1 AND deleted_at = $2", id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE ideas SET deleted_at = NULL WHERE user_id = $1 AND deleted_at = $2", id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeleted permanently removes the user accounts deleted before the given
+// time and returns how many were removed. Accounts that still own ideas are
+// kept until those ideas have been purged.
+func (m UserModal) PurgeDeleted(before time.Time) (int64, error) {
+	query := `DELETE FROM users
+	          WHERE deleted_at < $1
+	          AND NOT EXISTS (SELECT 1 FROM ideas WHERE ideas.user_id = users.id)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+
+	for rows.Next() {
+		var value string
+
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued at a time
+const RecoveryCodeCount = 10
+
+var ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+
+// TwoFactor is a user's TOTP enrolment. It is pending until EnabledAt is set
+// by confirming a first code.
+type TwoFactor struct {
+	UserID       uuid.UUID
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+}
+
+// Enabled reports whether the enrolment has been confirmed
+func (tf *TwoFactor) Enabled() bool {
+	return tf != nil && tf.EnabledAt != nil
+}
+
+func ValidateTwoFactorCode(v *validator.Validator, code, recoveryCode string) {
+	v.Check(code != "" || recoveryCode != "", "code", "must be provided")
+	v.Check(code == "" || recoveryCode == "", "code", "must not be given together with a recovery code")
+	v.Check(code == "" || len(code) == 6, "code", "must be 6 digits long")
+}
+
+type TwoFactorModel struct {
+	DB *sql.DB
+}
+
+func (m TwoFactorModel) Get(userID uuid.UUID) (*TwoFactor, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step
+	          FROM user_two_factor
+	          WHERE user_id = $1`
+
+	var tf TwoFactor
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.EnabledAt, &tf.LastUsedStep)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tf, nil
+}
+
+// SetPending stores a new secret awaiting confirmation, replacing any earlier
+// one that was never confirmed. It returns ErrTwoFactorEnabled if the user
+// already has two-factor authentication enabled.
+func (m TwoFactorModel) SetPending(userID uuid.UUID, secret string) error {
+	query := `INSERT INTO user_two_factor (user_id, secret)
+	          VALUES ($1, $2)
+	          ON CONFLICT (user_id) DO UPDATE
+	          SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+	          WHERE user_two_factor.enabled_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// UseStep records that the code for a time step was accepted. It reports
+// false when that step or a later one was already used, so that a code
+// cannot be replayed.
+func (m TwoFactorModel) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE user_two_factor
+	          SET last_used_step = $2
+	          WHERE user_id = $1 AND last_used_step < $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Enable confirms the user's pending enrolment and issues a fresh set of
+// recovery codes, which are only ever returned here.
+func (m TwoFactorModel) Enable(userID uuid.UUID) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE user_two_factor SET enabled_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, invalidating
+// the old ones
+func (m TwoFactorModel) RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+// UseRecoveryCode spends one of the user's recovery codes. It reports false
+// if the code is unknown or was already used.
+func (m TwoFactorModel) UseRecoveryCode(userID uuid.UUID, code string) (bool, error) {
+	query := `UPDATE two_factor_recovery_codes
+	          SET used_at = NOW()
+	          WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hashRecoveryCode(code), userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RemainingRecoveryCodes counts the user's unused recovery codes
+func (m TwoFactorModel) RemainingRecoveryCodes(userID uuid.UUID) (int, error) {
+	query := `SELECT count(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+// Delete turns two-factor authentication off for the user
+func (m TwoFactorModel) Delete(userID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_two_factor WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		randomBytes := make([]byte, 10)
+
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Shown as two groups of eight characters, e.g. ABCD2345-EFGH6789
+		code := base32.StdEncoding.EncodeToString(randomBytes)
+		codes[i] = code[:8] + "-" + code[8:]
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO two_factor_recovery_codes (code_hash, user_id) VALUES ($1, $2)`, hashRecoveryCode(codes[i]), userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. Codes are compared
+// without their separator and case, so they can be typed either way.
+func hashRecoveryCode(code string) []byte {
+	normalised := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	hash := sha256.Sum256([]byte(normalised))
+	return hash[:]
+}
@@ -116,7 +116,7 @@ func (m UserModal) Insert(user *User) error {
 func (m UserModal) GetByEmail(email string) (*User, error) {
 	query := `SELECT id, created_at, user_name, email, password_hash, user_type, activated, has_profile_created, version
       		  FROM users
-      		  WHERE email = $1`
+      		  WHERE email = $1 AND deleted_at IS NULL`
 
 	var user User
 
@@ -145,6 +145,38 @@ func (m UserModal) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+func (m UserModal) Get(id uuid.UUID) (*User, error) {
+	query := `SELECT id, created_at, user_name, email, password_hash, user_type, activated, has_profile_created, version
+      		  FROM users
+      		  WHERE id = $1 AND deleted_at IS NULL`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.UserName,
+		&user.Email,
+		&user.Password.hash,
+		&user.UserType,
+		&user.Activated,
+		&user.HasProfileCreated,
+		&user.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
 func (m UserModal) Update(user *User) error {
 	query := `UPDATE users
 			SET user_name = $1, email = $2, password_hash = $3, activated = $4, has_profile_created= $5, version = version + 1
@@ -178,6 +210,54 @@ func (m UserModal) Update(user *User) error {
 	return nil
 }
 
+// Delete moves the user's account to the trash together with their profile
+// and ideas, and signs them out. Everything deleted here shares the same
+// deleted_at, which is how Restore tells it apart from items the user had
+// deleted before.
+func (m UserModal) Delete(id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+
+	query := `UPDATE users SET deleted_at = NOW(), version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING deleted_at`
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(&deletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE user_profiles SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL", id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE ideas SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL", id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM tokens WHERE user_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (m UserModal) GetForToken(tokenScope, tokenPlainText string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlainText))
 
@@ -187,7 +267,8 @@ func (m UserModal) GetForToken(tokenScope, tokenPlainText string) (*User, error)
 	ON users.id = tokens.user_id
 	WHERE tokens.hash = $1
 	AND tokens.scope = $2
-	AND tokens.expiry > $3`
+	AND tokens.expiry > $3
+	AND users.deleted_at IS NULL`
 
 	args := []any{tokenHash[:], tokenScope, time.Now()}
 
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/OpenConnectOUSL/backend-api-v1/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	VoteUp   = 1
+	VoteDown = -1
+)
+
+func ValidateVote(v *validator.Validator, value int) {
+	v.Check(validator.PermittedValue(value, VoteUp, VoteDown), "value", "must be 1 or -1")
+}
+
+type VoteModel struct {
+	DB *sql.DB
+}
+
+// Set records the user's vote on an idea, replacing any earlier vote, and
+// refreshes the totals stored on the idea.
+func (m VoteModel) Set(ideaID, userID uuid.UUID, value int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockIdea(ctx, tx, ideaID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO idea_votes (idea_id, user_id, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (idea_id, user_id) DO UPDATE SET value = EXCLUDED.value, created_at = NOW()`,
+		ideaID, userID, value)
+	if err != nil {
+		return err
+	}
+
+	err = m.recount(ctx, tx, ideaID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete withdraws the user's vote on an idea.
+func (m VoteModel) Delete(ideaID, userID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockIdea(ctx, tx, ideaID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM idea_votes WHERE idea_id = $1 AND user_id = $2`, ideaID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = m.recount(ctx, tx, ideaID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockIdea locks the idea's row until the transaction ends. Votes on the same
+// idea then take turns, so each recount sees every vote committed before it
+// and none is missed.
+func (m VoteModel) lockIdea(ctx context.Context, tx *sql.Tx, ideaID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `SELECT id FROM ideas WHERE id = $1 FOR UPDATE`, ideaID)
+	return err
+}
+
+func (m VoteModel) recount(ctx context.Context, tx *sql.Tx, ideaID uuid.UUID) error {
+	query := `
+		UPDATE ideas
+		SET upvotes = (SELECT COUNT(*) FROM idea_votes WHERE idea_id = $1 AND value = 1),
+		    downvotes = (SELECT COUNT(*) FROM idea_votes WHERE idea_id = $1 AND value = -1)
+		WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, ideaID)
+	return err
+}
+
+// GetForUser returns the user's votes on the given ideas keyed by idea ID.
+// Ideas the user has not voted on are absent from the map.
+func (m VoteModel) GetForUser(userID uuid.UUID, ideaIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	votes := make(map[uuid.UUID]int)
+	if len(ideaIDs) == 0 {
+		return votes, nil
+	}
+
+	ids := make([]string, len(ideaIDs))
+	for i, id := range ideaIDs {
+		ids[i] = id.String()
+	}
+
+	query := `SELECT idea_id, value FROM idea_votes WHERE user_id = $1 AND idea_id = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ideaID uuid.UUID
+		var value int
+
+		err := rows.Scan(&ideaID, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		votes[ideaID] = value
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("expired token")
+)
+
+// Claims are the fields carried by a signed access token. Subject is the user
+// ID and SessionID the session the token was issued for.
+type Claims struct {
+	ID          string   `json:"jti"`
+	Subject     string   `json:"sub"`
+	SessionID   string   `json:"sid"`
+	Activated   bool     `json:"act"`
+	Permissions []string `json:"perms"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Signer signs tokens with the Ed25519 key named by its key ID and verifies
+// tokens signed with any of its keys. Keeping the previous key around after
+// switching to a new one lets tokens it signed be used until they expire.
+type Signer struct {
+	keys       map[string]ed25519.PrivateKey
+	currentKID string
+}
+
+// NewSigner parses a space separated list of keys, each given as
+// kid:base64 where the base64 part is an Ed25519 seed or private key. New
+// tokens are signed with the key named currentKID, or with the first key when
+// it is empty.
+func NewSigner(keySpec, currentKID string) (*Signer, error) {
+	s := &Signer{keys: make(map[string]ed25519.PrivateKey)}
+
+	for _, field := range strings.Fields(keySpec) {
+		kid, encoded, ok := strings.Cut(field, ":")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("signing key %q must be given as kid:base64", field)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q is not valid base64: %w", kid, err)
+		}
+
+		switch len(raw) {
+		case ed25519.SeedSize:
+			s.keys[kid] = ed25519.NewKeyFromSeed(raw)
+		case ed25519.PrivateKeySize:
+			s.keys[kid] = ed25519.PrivateKey(raw)
+		default:
+			return nil, fmt.Errorf("signing key %q must be a %d byte seed or %d byte private key", kid, ed25519.SeedSize, ed25519.PrivateKeySize)
+		}
+
+		if currentKID == "" {
+			currentKID = kid
+		}
+	}
+
+	if len(s.keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	if _, ok := s.keys[currentKID]; !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", currentKID)
+	}
+
+	s.currentKID = currentKID
+
+	return s, nil
+}
+
+// Sign encodes the claims as a JWT signed with the current key
+func (s *Signer) Sign(claims Claims) (string, error) {
+	headerJSON, err := json.Marshal(header{Alg: "EdDSA", Typ: "JWT", Kid: s.currentKID})
+	if err != nil {
+		return "", err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerJSON) + "." + encode(claimsJSON)
+	signature := ed25519.Sign(s.keys[s.currentKID], []byte(signingInput))
+
+	return signingInput + "." + encode(signature), nil
+}
+
+// Verify checks the token's signature against the key named in its header
+// and returns its claims unless it has expired at now.
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil || h.Alg != "EdDSA" {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := s.keys[h.Kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+// LooksSigned reports whether the token has the shape of a JWT rather than an
+// opaque token
+func LooksSigned(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJSON(part string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
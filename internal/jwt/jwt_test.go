@@ -0,0 +1,192 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey returns a kid:base64 key spec for a seed made of the given byte
+func testKey(kid string, b byte) string {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = b
+	}
+	return kid + ":" + base64.StdEncoding.EncodeToString(seed)
+}
+
+func newTestSigner(t *testing.T, keySpec, currentKID string) *Signer {
+	t.Helper()
+
+	s, err := NewSigner(keySpec, currentKID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testClaims(now time.Time) Claims {
+	return Claims{
+		ID:          "c6d0f1c4-8f6e-4a53-9f0f-1b5a1d8b8f43",
+		Subject:     "4b3f8e2a-33a4-4d4b-9a8e-2b7c6c0f1d11",
+		SessionID:   "9e2d6a7b-5c1f-4f0e-8a3b-6d4c2e1f0a99",
+		Activated:   true,
+		Permissions: []string{"ideas:read", "ideas:write"},
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(15 * time.Minute).Unix(),
+	}
+}
+
+// resign replaces the token's header and claims segments and signs them with
+// the key, as an attacker holding a key the server does not trust would
+func resign(key ed25519.PrivateKey, headerJSON, claimsJSON string) string {
+	signingInput := encode([]byte(headerJSON)) + "." + encode([]byte(claimsJSON))
+	return signingInput + "." + encode(ed25519.Sign(key, []byte(signingInput)))
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := newTestSigner(t, testKey("k1", 1), "")
+
+	token, err := s.Sign(testClaims(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !LooksSigned(token) {
+		t.Fatalf("LooksSigned(%q) = false", token)
+	}
+
+	claims, err := s.Verify(token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := testClaims(now)
+	if claims.Subject != want.Subject || claims.SessionID != want.SessionID || !claims.Activated ||
+		strings.Join(claims.Permissions, ",") != strings.Join(want.Permissions, ",") || claims.ExpiresAt != want.ExpiresAt {
+		t.Fatalf("got claims %+v, want %+v", claims, want)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := newTestSigner(t, testKey("k1", 1), "")
+
+	token, err := s.Sign(testClaims(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	other := newTestSigner(t, testKey("k2", 2), "")
+	otherToken, err := other.Sign(testClaims(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed by a key the server does not have, claiming to be k1
+	forgedKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	forged := resign(forgedKey, `{"alg":"EdDSA","typ":"JWT","kid":"k1"}`, `{"sub":"attacker","exp":9999999999}`)
+
+	tamperedClaims := encode([]byte(`{"sub":"attacker","perms":["users:admin"],"exp":9999999999}`))
+
+	flippedSignature := []byte(parts[2])
+	if flippedSignature[0] == 'A' {
+		flippedSignature[0] = 'B'
+	} else {
+		flippedSignature[0] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		now   time.Time
+		want  error
+	}{
+		{"tampered claims", parts[0] + "." + tamperedClaims + "." + parts[2], now, ErrInvalidToken},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + string(flippedSignature), now, ErrInvalidToken},
+		{"missing signature", parts[0] + "." + parts[1] + ".", now, ErrInvalidToken},
+		{"unknown kid", otherToken, now, ErrInvalidToken},
+		{"forged with untrusted key", forged, now, ErrInvalidToken},
+		{"alg none", encode([]byte(`{"alg":"none","typ":"JWT","kid":"k1"}`)) + "." + parts[1] + ".", now, ErrInvalidToken},
+		{"alg none keeping signature", encode([]byte(`{"alg":"none","typ":"JWT","kid":"k1"}`)) + "." + parts[1] + "." + parts[2], now, ErrInvalidToken},
+		{"alg HS256", encode([]byte(`{"alg":"HS256","typ":"JWT","kid":"k1"}`)) + "." + parts[1] + "." + parts[2], now, ErrInvalidToken},
+		{"two segments", parts[0] + "." + parts[1], now, ErrInvalidToken},
+		{"four segments", token + ".x", now, ErrInvalidToken},
+		{"not base64", "!!!." + parts[1] + "." + parts[2], now, ErrInvalidToken},
+		{"expired", token, now.Add(15 * time.Minute), ErrExpiredToken},
+		{"long expired", token, now.Add(24 * time.Hour), ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := s.Verify(tt.token, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			if claims != nil {
+				t.Fatalf("got claims %+v, want nil", claims)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	old := newTestSigner(t, testKey("k1", 1), "")
+	token, err := old.Sign(testClaims(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After rotation new tokens use k2, and tokens signed with k1 stay valid
+	// for as long as k1 is configured
+	rotated := newTestSigner(t, testKey("k1", 1)+" "+testKey("k2", 2), "k2")
+
+	if _, err := rotated.Verify(token, now); err != nil {
+		t.Fatalf("token signed with the previous key: %v", err)
+	}
+
+	newToken, err := rotated.Sign(testClaims(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := old.Verify(newToken, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token signed with the new key verified by the old signer: got %v, want %v", err, ErrInvalidToken)
+	}
+
+	retired := newTestSigner(t, testKey("k2", 2), "")
+
+	if _, err := retired.Verify(token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token signed with a retired key: got %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestNewSignerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		keySpec    string
+		currentKID string
+	}{
+		{"no keys", "", ""},
+		{"missing kid", ":" + base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)), ""},
+		{"missing separator", base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)), ""},
+		{"invalid base64", "k1:!!!", ""},
+		{"wrong key size", "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 16)), ""},
+		{"unknown current kid", testKey("k1", 1), "k2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSigner(tt.keySpec, tt.currentKID); err == nil {
+				t.Fatal("got nil error")
+			}
+		})
+	}
+}
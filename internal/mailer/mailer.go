@@ -31,6 +31,10 @@ func (m Mailer) Send(recipient, templateType string, data any) error {
 		tempFile = "./internal/mailer/templates/user_welcome.tmpl"
 	} else if templateType == "password_reset" {
 		tempFile = "./internal/mailer/templates/token_password_reset.tmpl"
+	} else if templateType == "join_request" {
+		tempFile = "./internal/mailer/templates/join_request.tmpl"
+	} else if templateType == "account_unlock" {
+		tempFile = "./internal/mailer/templates/token_account_unlock.tmpl"
 	}
 	tmpl, err := template.ParseFiles(tempFile)
 	if err != nil {
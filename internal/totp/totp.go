@@ -0,0 +1,93 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// Period is how long each code is valid, in seconds
+	Period = 30
+	// Digits is the length of a code
+	Digits = 6
+	// Skew is the number of periods before and after the current one whose
+	// codes are also accepted, to allow for clock drift
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded secret of 160 bits, as
+// recommended for HMAC-SHA1 by RFC 4226
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// KeyURI returns the otpauth:// URI that authenticator apps read from a QR
+// code
+func KeyURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(Period))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Validate checks the code against the secret at time t. It returns the time
+// step the code belongs to, so that callers can refuse to accept a step
+// twice, and false if the code does not match.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+
+	current := t.Unix() / Period
+
+	for step := current - Skew; step <= current+Skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(generate(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// generate computes the code for a time step as described in RFC 6238
+func generate(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range Digits {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
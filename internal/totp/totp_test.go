@@ -0,0 +1,129 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key from the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8 digit codes; 6 digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("Validate(%q) at %d: got false, want true", tt.code, tt.unix)
+			continue
+		}
+
+		if want := tt.unix / Period; step != want {
+			t.Errorf("Validate(%q) at %d: got step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	// 287082 is the code for step 1 (t = 30..59)
+	tests := []struct {
+		name string
+		unix int64
+		want bool
+	}{
+		{"one step early", 0, true},
+		{"current step", 45, true},
+		{"one step late", 89, true},
+		{"two steps late", 90, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, "287082", time.Unix(tt.unix, 0))
+			if ok != tt.want {
+				t.Fatalf("got %t, want %t", ok, tt.want)
+			}
+			if ok && step != 1 {
+				t.Fatalf("got step %d, want 1", step)
+			}
+		})
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "287083"},
+		{"too short", rfcSecret, "28708"},
+		{"too long", rfcSecret, "2870820"},
+		{"empty", rfcSecret, ""},
+		{"invalid secret", "not base32!", "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok {
+				t.Fatal("got true, want false")
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not unpadded base32: %v", err)
+	}
+
+	if len(key) != 20 {
+		t.Fatalf("got %d byte key, want 20", len(key))
+	}
+
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret == other {
+		t.Fatal("two secrets are the same")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("Open Connect", "ada@example.com", rfcSecret)
+
+	for _, want := range []string{
+		"otpauth://totp/Open%20Connect:ada@example.com?",
+		"secret=" + rfcSecret,
+		"issuer=Open+Connect",
+		"digits=6",
+		"period=30",
+	} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("%q does not contain %q", uri, want)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_comments_parent_id;
+DROP INDEX IF EXISTS idx_comments_idea_id;
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_ideas_votes;
+
+ALTER TABLE ideas
+    DROP COLUMN IF EXISTS votes,
+    DROP COLUMN IF EXISTS upvotes,
+    DROP COLUMN IF EXISTS downvotes;
+
+DROP INDEX IF EXISTS idx_idea_votes_user_id;
+DROP TABLE IF EXISTS idea_votes;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS idea_votes (
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (idea_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_votes_user_id ON idea_votes(user_id);
+
+-- Vote totals are kept on the idea row so listings can sort by popularity cheaply
+ALTER TABLE ideas
+    ADD COLUMN IF NOT EXISTS upvotes INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS downvotes INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS votes INT GENERATED ALWAYS AS (upvotes - downvotes) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_ideas_votes ON ideas(votes);
@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE code = 'ideas:moderate';
+
+DROP INDEX IF EXISTS idx_idea_status_transitions_idea_id;
+DROP TABLE IF EXISTS idea_status_transitions;
+
+ALTER TABLE ideas DROP CONSTRAINT IF EXISTS valid_idea_status;
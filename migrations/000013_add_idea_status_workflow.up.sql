@@ -0,0 +1,20 @@
+UPDATE ideas SET status = 'pending'
+WHERE status NOT IN ('draft', 'pending', 'approved', 'rejected', 'in_progress', 'completed', 'archived');
+
+ALTER TABLE ideas
+    ADD CONSTRAINT valid_idea_status
+    CHECK (status IN ('draft', 'pending', 'approved', 'rejected', 'in_progress', 'completed', 'archived'));
+
+CREATE TABLE IF NOT EXISTS idea_status_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_status_transitions_idea_id ON idea_status_transitions(idea_id);
+
+INSERT INTO permissions (id, code) VALUES (gen_random_uuid(), 'ideas:moderate');
@@ -0,0 +1,8 @@
+INSERT INTO permissions (id, code) VALUES (gen_random_uuid(), 'ideas:admin');
+
+-- Existing admin accounts keep the ability to manage every idea
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id
+FROM users, permissions
+WHERE users.user_type = 'admin' AND permissions.code = 'ideas:admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- Each row is a snapshot of an idea as it was at the given version, taken
+-- just before the idea moved on to the next version.
+CREATE TABLE IF NOT EXISTS idea_revisions (
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    tags TEXT[] NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    learning_outcome TEXT,
+    recommended_level VARCHAR(50),
+    github_link TEXT,
+    website_link TEXT,
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    replaced_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (idea_id, version)
+);
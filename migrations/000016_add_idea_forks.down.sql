@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_ideas_idea_source_id;
+
+ALTER TABLE ideas DROP CONSTRAINT IF EXISTS fk_ideas_source;
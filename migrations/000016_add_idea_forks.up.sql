@@ -0,0 +1,18 @@
+-- idea_source_id used to hold the uploaded PDF's ID; move those values into
+-- the pdf column so idea_source_id can point at the idea this one was forked from.
+UPDATE ideas
+SET pdf = idea_source_id::text
+WHERE idea_source_id IS NOT NULL
+  AND (pdf IS NULL OR pdf = '')
+  AND NOT EXISTS (SELECT 1 FROM ideas src WHERE src.id = ideas.idea_source_id);
+
+UPDATE ideas
+SET idea_source_id = NULL
+WHERE idea_source_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM ideas src WHERE src.id = ideas.idea_source_id);
+
+ALTER TABLE ideas
+    ADD CONSTRAINT fk_ideas_source
+    FOREIGN KEY (idea_source_id) REFERENCES ideas(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ideas_idea_source_id ON ideas(idea_source_id);
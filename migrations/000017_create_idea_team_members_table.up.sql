@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS idea_team_members (
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'invited',
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP(0) WITH TIME ZONE,
+    version INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (idea_id, user_id),
+    CONSTRAINT valid_team_role CHECK (role IN ('lead', 'member', 'mentor')),
+    CONSTRAINT valid_team_status CHECK (status IN ('invited', 'accepted', 'declined'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_team_members_user_id ON idea_team_members(user_id);
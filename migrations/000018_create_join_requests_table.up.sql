@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS join_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    response TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP(0) WITH TIME ZONE,
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT valid_join_request_status CHECK (status IN ('pending', 'accepted', 'rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_join_requests_idea_id ON join_requests(idea_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_join_requests_pending ON join_requests(idea_id, user_id) WHERE status = 'pending';
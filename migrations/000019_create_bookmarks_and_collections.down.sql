@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS collection_ideas;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS bookmarks;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS bookmarks (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idea_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_idea_id ON bookmarks(idea_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    visibility VARCHAR(20) NOT NULL DEFAULT 'private',
+    share_token TEXT UNIQUE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT valid_collection_visibility CHECK (visibility IN ('private', 'link'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_user_id ON collections(user_id);
+
+CREATE TABLE IF NOT EXISTS collection_ideas (
+    collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    added_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (collection_id, idea_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_ideas_idea_id ON collection_ideas(idea_id);
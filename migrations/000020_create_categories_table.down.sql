@@ -0,0 +1,10 @@
+DELETE FROM permissions WHERE code = 'categories:manage';
+
+ALTER TABLE ideas DROP CONSTRAINT IF EXISTS fk_ideas_category;
+
+UPDATE ideas
+SET category = categories.name
+FROM categories
+WHERE categories.slug = ideas.category;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    parent_id UUID REFERENCES categories(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT valid_category_slug CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$')
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+-- Map the free-text categories onto slugs, so "AI", "ai" and " Ai " all
+-- become the "ai" category. The most common spelling becomes the display name.
+INSERT INTO categories (slug, name)
+SELECT DISTINCT ON (slug) slug, name
+FROM (
+    SELECT COALESCE(NULLIF(trim(BOTH '-' FROM left(regexp_replace(lower(trim(category)), '[^a-z0-9]+', '-', 'g'), 50)), ''), 'uncategorized') AS slug,
+           COALESCE(NULLIF(trim(category), ''), 'Uncategorized') AS name,
+           count(*) AS uses
+    FROM ideas
+    GROUP BY 1, 2
+) existing
+ORDER BY slug, uses DESC, name;
+
+UPDATE ideas
+SET category = COALESCE(NULLIF(trim(BOTH '-' FROM left(regexp_replace(lower(trim(category)), '[^a-z0-9]+', '-', 'g'), 50)), ''), 'uncategorized');
+
+-- Keep older revisions restorable under the new slugs
+UPDATE idea_revisions
+SET category = COALESCE(NULLIF(trim(BOTH '-' FROM left(regexp_replace(lower(trim(category)), '[^a-z0-9]+', '-', 'g'), 50)), ''), 'uncategorized');
+
+ALTER TABLE ideas
+    ADD CONSTRAINT fk_ideas_category
+    FOREIGN KEY (category) REFERENCES categories(slug) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+INSERT INTO permissions (id, code) VALUES (gen_random_uuid(), 'categories:manage');
+
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id
+FROM users, permissions
+WHERE users.user_type = 'admin' AND permissions.code = 'categories:manage'
+ON CONFLICT DO NOTHING;
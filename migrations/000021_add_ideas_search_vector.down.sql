@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_ideas_search_vector;
+DROP TRIGGER IF EXISTS ideas_search_vector_trigger ON ideas;
+DROP FUNCTION IF EXISTS ideas_search_vector_update();
+ALTER TABLE ideas DROP COLUMN IF EXISTS search_vector;
+
+CREATE INDEX IF NOT EXISTS ideas_title_idx ON ideas USING GIN (to_tsvector('simple', title));
@@ -0,0 +1,28 @@
+-- The old title index used the 'simple' configuration, so it never matched the
+-- 'english' queries the API runs and was never used.
+DROP INDEX IF EXISTS ideas_title_idx;
+
+ALTER TABLE ideas ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION ideas_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', array_to_string(COALESCE(NEW.tags, '{}'), ' ')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'C') ||
+        setweight(to_tsvector('english', COALESCE(NEW.learning_outcome, '')), 'D');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ideas_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, description, learning_outcome, tags ON ideas
+    FOR EACH ROW EXECUTE FUNCTION ideas_search_vector_update();
+
+UPDATE ideas SET search_vector =
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', array_to_string(COALESCE(tags, '{}'), ' ')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(learning_outcome, '')), 'D');
+
+CREATE INDEX IF NOT EXISTS idx_ideas_search_vector ON ideas USING GIN (search_vector);
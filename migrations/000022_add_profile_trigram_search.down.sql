@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_skills_skill_trgm;
+DROP INDEX IF EXISTS idx_user_profiles_program_trgm;
+DROP INDEX IF EXISTS idx_user_profiles_faculty_trgm;
+DROP INDEX IF EXISTS idx_user_profiles_bio_trgm;
+DROP INDEX IF EXISTS idx_user_profiles_title_trgm;
+DROP INDEX IF EXISTS idx_user_profiles_name_trgm;
+DROP INDEX IF EXISTS idx_users_user_name_trgm;
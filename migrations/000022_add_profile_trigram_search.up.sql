@@ -0,0 +1,10 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_user_name_trgm ON users USING GIN (user_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_name_trgm ON user_profiles
+    USING GIN ((COALESCE(firstname, '') || ' ' || COALESCE(lastname, '')) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_title_trgm ON user_profiles USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_bio_trgm ON user_profiles USING GIN (bio gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_faculty_trgm ON user_profiles USING GIN (faculty gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_program_trgm ON user_profiles USING GIN (program gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_skills_skill_trgm ON user_skills USING GIN (skill gin_trgm_ops);
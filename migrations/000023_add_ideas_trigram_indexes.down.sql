@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ideas_description_trgm;
+DROP INDEX IF EXISTS idx_ideas_title_trgm;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_ideas_title_trgm ON ideas USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_ideas_description_trgm ON ideas USING GIN (description gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP MATERIALIZED VIEW IF EXISTS idea_activity_stats;
+DROP INDEX IF EXISTS idx_idea_revisions_replaced_at;
+DROP TABLE IF EXISTS idea_views;
@@ -0,0 +1,51 @@
+-- One row per viewer per idea per day. The viewer is the user id, or the
+-- client IP for anonymous requests.
+CREATE TABLE IF NOT EXISTS idea_views (
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    viewer TEXT NOT NULL,
+    viewed_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    viewed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (idea_id, viewer, viewed_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_views_viewed_at ON idea_views(viewed_at);
+CREATE INDEX IF NOT EXISTS idx_idea_revisions_replaced_at ON idea_revisions(replaced_at);
+
+-- Views, edits and creation of each idea within each feed period, decayed
+-- exponentially over the length of the period so that recent activity counts
+-- for more. Only ideas with some activity in the period are included. The
+-- view is refreshed periodically by the API server.
+CREATE MATERIALIZED VIEW IF NOT EXISTS idea_activity_stats AS
+WITH periods (period, span) AS (
+    VALUES ('24h', INTERVAL '24 hours'), ('7d', INTERVAL '7 days'), ('30d', INTERVAL '30 days')
+)
+SELECT i.id AS idea_id,
+       p.period,
+       COALESCE(v.views, 0) AS views,
+       COALESCE(e.edits, 0) AS edits,
+       COALESCE(v.decayed, 0)::float8 AS decayed_views,
+       COALESCE(e.decayed, 0)::float8 AS decayed_edits,
+       CASE WHEN i.created_at >= NOW() - p.span
+            THEN exp(-extract(epoch FROM NOW() - i.created_at) / extract(epoch FROM p.span))
+            ELSE 0 END::float8 AS decayed_creation,
+       GREATEST(i.created_at, i.updated_at, e.last_edited_at) AS last_active_at,
+       NOW() AS refreshed_at
+FROM ideas i
+CROSS JOIN periods p
+LEFT JOIN LATERAL (
+    SELECT count(*) AS views,
+           sum(exp(-extract(epoch FROM NOW() - viewed_at) / extract(epoch FROM p.span))) AS decayed
+    FROM idea_views
+    WHERE idea_id = i.id AND viewed_at >= NOW() - p.span
+) v ON TRUE
+LEFT JOIN LATERAL (
+    SELECT count(*) AS edits,
+           max(replaced_at) AS last_edited_at,
+           sum(exp(-extract(epoch FROM NOW() - replaced_at) / extract(epoch FROM p.span))) AS decayed
+    FROM idea_revisions
+    WHERE idea_id = i.id AND replaced_at >= NOW() - p.span
+) e ON TRUE
+WHERE v.views > 0 OR e.edits > 0 OR i.created_at >= NOW() - p.span;
+
+-- Required to refresh the view concurrently
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idea_activity_stats_idea_period ON idea_activity_stats(idea_id, period);
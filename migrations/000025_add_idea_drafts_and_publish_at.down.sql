@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_ideas_publish_at;
+
+ALTER TABLE ideas DROP COLUMN IF EXISTS publish_at;
+
+-- Drafts saved without a category are kept under the same fallback
+-- category the taxonomy migration used for ideas without one
+INSERT INTO categories (slug, name)
+SELECT 'uncategorized', 'Uncategorized'
+WHERE EXISTS (SELECT 1 FROM ideas WHERE category IS NULL)
+ON CONFLICT (slug) DO NOTHING;
+
+UPDATE ideas SET category = 'uncategorized' WHERE category IS NULL;
+ALTER TABLE ideas ALTER COLUMN category SET NOT NULL;
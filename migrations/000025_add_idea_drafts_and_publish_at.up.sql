@@ -0,0 +1,8 @@
+-- Drafts may be saved before a category has been chosen
+ALTER TABLE ideas ALTER COLUMN category DROP NOT NULL;
+
+-- Drafts with a publish_at are submitted for review once it has passed
+ALTER TABLE ideas ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_ideas_publish_at ON ideas(publish_at)
+WHERE status = 'draft' AND publish_at IS NOT NULL;
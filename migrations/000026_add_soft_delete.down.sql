@@ -0,0 +1,19 @@
+DELETE FROM permissions WHERE code = 'users:admin';
+
+ALTER TABLE ideas DROP CONSTRAINT IF EXISTS fk_ideas_user;
+ALTER TABLE ideas
+    ADD CONSTRAINT fk_ideas_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_user_profiles_deleted_at;
+DROP INDEX IF EXISTS idx_ideas_deleted_at;
+
+-- Items still in the trash would otherwise reappear
+DELETE FROM ideas WHERE deleted_at IS NOT NULL;
+DELETE FROM user_profiles WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE ideas DROP COLUMN IF EXISTS deleted_at;
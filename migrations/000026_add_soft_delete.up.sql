@@ -0,0 +1,27 @@
+-- Deleted ideas, profiles and users are kept in the trash until the purge job
+-- removes them once the retention window has passed
+ALTER TABLE ideas ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_ideas_deleted_at ON ideas(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_user_profiles_deleted_at ON user_profiles(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Removing a user must no longer take their ideas with it; the purge job
+-- removes the ideas, and their files, first
+ALTER TABLE ideas DROP CONSTRAINT IF EXISTS fk_ideas_user;
+ALTER TABLE ideas
+    ADD CONSTRAINT fk_ideas_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+INSERT INTO permissions (id, code) VALUES (gen_random_uuid(), 'users:admin');
+
+-- Admin accounts can restore deleted user accounts. Like ideas:moderate and
+-- categories:manage, this is only granted to the admins that exist when the
+-- migration runs; accounts made admin later must be granted it explicitly.
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id
+FROM users, permissions
+WHERE users.user_type = 'admin' AND permissions.code = 'users:admin'
+ON CONFLICT DO NOTHING;
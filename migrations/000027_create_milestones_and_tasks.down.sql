@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_idea_tasks_assignee_id;
+DROP INDEX IF EXISTS idx_idea_tasks_milestone_id;
+DROP INDEX IF EXISTS idx_idea_tasks_board;
+DROP TABLE IF EXISTS idea_tasks;
+
+DROP INDEX IF EXISTS idx_idea_milestones_idea_id;
+DROP TABLE IF EXISTS idea_milestones;
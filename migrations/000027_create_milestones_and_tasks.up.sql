@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS idea_milestones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    due_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT valid_milestone_status CHECK (status IN ('open', 'completed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_milestones_idea_id ON idea_milestones(idea_id);
+
+-- Tasks are the cards of the idea's kanban board: status is the column and
+-- position the order within it, starting at 0
+CREATE TABLE IF NOT EXISTS idea_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    idea_id UUID NOT NULL REFERENCES ideas(id) ON DELETE CASCADE,
+    milestone_id UUID REFERENCES idea_milestones(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'todo',
+    position INT NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    CONSTRAINT valid_task_status CHECK (status IN ('todo', 'in_progress', 'review', 'done')),
+    CONSTRAINT valid_task_position CHECK (position >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idea_tasks_board ON idea_tasks(idea_id, status, position);
+CREATE INDEX IF NOT EXISTS idx_idea_tasks_milestone_id ON idea_tasks(milestone_id);
+CREATE INDEX IF NOT EXISTS idx_idea_tasks_assignee_id ON idea_tasks(assignee_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_tokens_user_scope;
+DROP INDEX IF EXISTS idx_tokens_id;
+
+ALTER TABLE tokens
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS id;
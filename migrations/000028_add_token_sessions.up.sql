@@ -0,0 +1,10 @@
+-- Authentication tokens double as sessions, which users can list and revoke
+ALTER TABLE tokens
+    ADD COLUMN IF NOT EXISTS id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP(0) WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS user_agent text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip text NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tokens_id ON tokens(id);
+CREATE INDEX IF NOT EXISTS idx_tokens_user_scope ON tokens(user_id, scope);
@@ -0,0 +1,11 @@
+-- The access and refresh tokens issued at login form a family that shares one
+-- session. Used refresh tokens are kept so that a replay can be detected.
+ALTER TABLE tokens
+    ADD COLUMN IF NOT EXISTS family_id UUID,
+    ADD COLUMN IF NOT EXISTS used_at TIMESTAMP(0) WITH TIME ZONE;
+
+UPDATE tokens SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tokens_family_id ON tokens(family_id);
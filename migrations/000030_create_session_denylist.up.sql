@@ -0,0 +1,8 @@
+-- Signed access tokens are verified without a database lookup, so revoking a
+-- session records it here until its last signed token has expired
+CREATE TABLE IF NOT EXISTS session_denylist (
+    session_id UUID PRIMARY KEY,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_denylist_expiry ON session_denylist(expiry);
@@ -0,0 +1,4 @@
+DELETE FROM tokens WHERE scope = 'two-factor';
+
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
@@ -0,0 +1,17 @@
+-- A TOTP secret is pending until the user confirms it with a first code.
+-- last_used_step stops a code from being accepted twice.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    secret text NOT NULL,
+    enabled_at TIMESTAMP(0) WITH TIME ZONE,
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    code_hash bytea PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    used_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes(user_id);
@@ -0,0 +1,4 @@
+DELETE FROM tokens WHERE scope = 'unlock';
+
+DROP TABLE IF EXISTS account_lockouts;
+DROP TABLE IF EXISTS login_failures;
@@ -0,0 +1,17 @@
+-- Failed logins are tracked by email, whether or not an account exists for
+-- it, and by IP address, to throttle guessing and lock accounts under attack
+CREATE TABLE IF NOT EXISTS login_failures (
+    id bigserial PRIMARY KEY,
+    email citext NOT NULL,
+    ip text NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_email ON login_failures(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_failures_ip ON login_failures(ip, created_at);
+
+CREATE TABLE IF NOT EXISTS account_lockouts (
+    email citext PRIMARY KEY,
+    locked_until TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- Fails while a deleted account and a newer one share an email address;
+-- purge or rename the deleted account first
+DROP INDEX IF EXISTS users_email_key;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
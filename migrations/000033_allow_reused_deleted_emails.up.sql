@@ -0,0 +1,5 @@
+-- An email address only has to be unique among accounts that are not in the
+-- trash, so that someone who deleted their account can register again
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users(email) WHERE deleted_at IS NULL;
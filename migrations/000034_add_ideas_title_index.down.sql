@@ -0,0 +1 @@
+DROP INDEX IF EXISTS ideas_title_idx;
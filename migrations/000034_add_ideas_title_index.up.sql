@@ -0,0 +1,3 @@
+-- The title filter runs to_tsvector('english', title) on its own, which the
+-- search_vector index cannot serve
+CREATE INDEX IF NOT EXISTS ideas_title_idx ON ideas USING GIN (to_tsvector('english', title));